@@ -0,0 +1,513 @@
+// The apiext package extends the rbxapijson model with fields of API dumps
+// that the model does not represent.
+//
+// Newer API dumps include fields such as ThreadSafety and Capabilities, as
+// well as tags that are objects rather than strings. The rbxapijson package
+// cannot decode such tags, and ignores the other fields. Decode removes these
+// fields from the dump before it is decoded by rbxapijson, and returns them
+// separately as a Root.
+package apiext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/robloxapi/rbxapi/rbxapijson"
+)
+
+// Key identifies an element of an API dump.
+type Key struct {
+	// Type is the type of the element: "Class", "Member", "Enum", or
+	// "EnumItem".
+	Type string
+	// Parent is the name of the class of a member, or the enum of an enum
+	// item. Empty for classes and enums.
+	Parent string
+	// Name is the name of the element.
+	Name string
+}
+
+// ClassKey returns the key of a class.
+func ClassKey(class string) Key { return Key{Type: "Class", Name: class} }
+
+// MemberKey returns the key of a member of a class.
+func MemberKey(class, member string) Key { return Key{Type: "Member", Parent: class, Name: member} }
+
+// EnumKey returns the key of an enum.
+func EnumKey(enum string) Key { return Key{Type: "Enum", Name: enum} }
+
+// EnumItemKey returns the key of an item of an enum.
+func EnumItemKey(enum, item string) Key { return Key{Type: "EnumItem", Parent: enum, Name: item} }
+
+// String returns the key in the form "Type:Name" or "Type:Parent.Name".
+func (k Key) String() string {
+	if k.Parent != "" {
+		return k.Type + ":" + k.Parent + "." + k.Name
+	}
+	return k.Type + ":" + k.Name
+}
+
+func (k Key) MarshalText() (text []byte, err error) {
+	return []byte(k.String()), nil
+}
+
+func (k *Key) UnmarshalText(text []byte) error {
+	s := string(text)
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return fmt.Errorf("invalid element key %q", s)
+	}
+	key := Key{Type: s[:i], Name: s[i+1:]}
+	switch key.Type {
+	case "Class", "Enum":
+	case "Member", "EnumItem":
+		j := strings.Index(key.Name, ".")
+		if j < 0 {
+			return fmt.Errorf("invalid element key %q", s)
+		}
+		key.Parent, key.Name = key.Name[:j], key.Name[j+1:]
+	default:
+		return fmt.Errorf("invalid element type %q", key.Type)
+	}
+	*k = key
+	return nil
+}
+
+// Less returns whether k is sorted before l.
+func (k Key) Less(l Key) bool {
+	if k.Type != l.Type {
+		return k.Type < l.Type
+	}
+	if k.Parent != l.Parent {
+		return k.Parent < l.Parent
+	}
+	return k.Name < l.Name
+}
+
+// TagPrefix is the prefix of the name of a field that holds the value of a tag
+// object. For example, the tag {"PreferredDescendantName": "Destroy"} is held
+// by the field "Tags.PreferredDescendantName".
+const TagPrefix = "Tags."
+
+// fieldNames is the set of extended fields other than tag values.
+var fieldNames = map[string]bool{
+	"ThreadSafety":      true,
+	"Capabilities":      true,
+	"ReadCapabilities":  true,
+	"WriteCapabilities": true,
+}
+
+// IsField returns whether name is the name of an extended field.
+func IsField(name string) bool {
+	return fieldNames[name] || strings.HasPrefix(name, TagPrefix)
+}
+
+// LookupField returns the name of the extended field that matches name
+// case-insensitively. ok is false if there is no such field.
+func LookupField(name string) (field string, ok bool) {
+	for field := range fieldNames {
+		if strings.EqualFold(field, name) {
+			return field, true
+		}
+	}
+	if len(name) > len(TagPrefix) && strings.EqualFold(name[:len(TagPrefix)], TagPrefix) {
+		return TagPrefix + name[len(TagPrefix):], true
+	}
+	return "", false
+}
+
+// Fields maps the name of each extended field of an element to its value. A
+// value is either a string or a []string. Within a list of changes, a nil
+// value indicates that the field is no longer reported.
+type Fields map[string]interface{}
+
+// Names returns the names of the fields, excluding tag values, sorted.
+func (f Fields) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		if !strings.HasPrefix(name, TagPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TagNames returns the names of the tag objects of the fields, without
+// TagPrefix, sorted.
+func (f Fields) TagNames() []string {
+	var names []string
+	for name := range f {
+		if strings.HasPrefix(name, TagPrefix) {
+			names = append(names, strings.TrimPrefix(name, TagPrefix))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AllNames returns the names of the fields, including tag values, sorted.
+func (f Fields) AllNames() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Text returns the value of a field as text. A list is joined with commas.
+// Returns an empty string if the field is not set.
+func (f Fields) Text(name string) string {
+	switch v := f[name].(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ", ")
+	}
+	return ""
+}
+
+// Tag returns the value of a tag object. Returns an empty string if the tag
+// is not set.
+func (f Fields) Tag(name string) string {
+	return f.Text(TagPrefix + name)
+}
+
+// Copy returns a copy of the fields.
+func (f Fields) Copy() Fields {
+	if f == nil {
+		return nil
+	}
+	c := make(Fields, len(f))
+	for name, value := range f {
+		if list, ok := value.([]string); ok {
+			value = append([]string(nil), list...)
+		}
+		c[name] = value
+	}
+	return c
+}
+
+// Equal returns whether a and b are equal values of a field.
+func Equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	case []string:
+		b, ok := b.([]string)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return a == nil && b == nil
+}
+
+func (f *Fields) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	fields := make(Fields, len(raw))
+	for name, value := range raw {
+		v, err := fieldValue(value)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		fields[name] = v
+	}
+	*f = fields
+	return nil
+}
+
+// fieldValue converts a decoded JSON value to the value of a field.
+func fieldValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return value, nil
+	case []interface{}:
+		list := make([]string, len(value))
+		for i, v := range value {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("list contains non-string value")
+			}
+			list[i] = s
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("unexpected value %v", value)
+}
+
+// Root maps each element of an API dump to its extended fields. Elements
+// without extended fields are omitted.
+type Root map[Key]Fields
+
+// Get returns the fields of an element. Returns nil if the element has no
+// fields.
+func (r Root) Get(key Key) Fields {
+	return r[key]
+}
+
+// Keys returns the key of each element, sorted.
+func (r Root) Keys() []Key {
+	keys := make([]Key, 0, len(r))
+	for key := range r {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Less(keys[j]) })
+	return keys
+}
+
+// Set sets the value of a field of an element. A nil value unsets the field.
+func (r Root) Set(key Key, name string, value interface{}) {
+	if value == nil {
+		if fields := r[key]; fields != nil {
+			delete(fields, name)
+			if len(fields) == 0 {
+				delete(r, key)
+			}
+		}
+		return
+	}
+	fields := r[key]
+	if fields == nil {
+		fields = Fields{}
+		r[key] = fields
+	}
+	fields[name] = value
+}
+
+// Copy returns a deep copy of the root.
+func (r Root) Copy() Root {
+	if r == nil {
+		return nil
+	}
+	c := make(Root, len(r))
+	for key, fields := range r {
+		c[key] = fields.Copy()
+	}
+	return c
+}
+
+// Select returns the fields of the elements of r that are described by
+// element, which is a class, member, enum, or enum item of an API dump. The
+// fields of the members of a class, or the items of an enum, are included.
+// parent is the name of the class or enum of a member or item. Returns nil if
+// there are no such fields.
+func (r Root) Select(parent string, element interface{}) Root {
+	var sel Root
+	add := func(key Key) {
+		if fields := r[key]; fields != nil {
+			if sel == nil {
+				sel = Root{}
+			}
+			sel[key] = fields.Copy()
+		}
+	}
+	switch e := element.(type) {
+	case *rbxapijson.Class:
+		add(ClassKey(e.Name))
+		for _, member := range e.Members {
+			add(MemberKey(e.Name, member.GetName()))
+		}
+	case *rbxapijson.Enum:
+		add(EnumKey(e.Name))
+		for _, item := range e.Items {
+			add(EnumItemKey(e.Name, item.Name))
+		}
+	case *rbxapijson.EnumItem:
+		add(EnumItemKey(parent, e.Name))
+	case interface{ GetName() string }:
+		add(MemberKey(parent, e.GetName()))
+	}
+	return sel
+}
+
+// Decode decodes an API dump in JSON format. Extended fields are removed from
+// the dump before it is decoded by rbxapijson, and are returned as ext.
+func Decode(r io.Reader) (root *rbxapijson.Root, ext Root, err error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var dump map[string]interface{}
+	jd := json.NewDecoder(bytes.NewReader(b))
+	// Preserve numbers exactly, so that they are encoded again as written.
+	jd.UseNumber()
+	if err := jd.Decode(&dump); err != nil {
+		return nil, nil, err
+	}
+	ext = Root{}
+	for _, class := range objects(dump["Classes"]) {
+		name, _ := class["Name"].(string)
+		extract(ext, ClassKey(name), class)
+		for _, member := range objects(class["Members"]) {
+			mname, _ := member["Name"].(string)
+			extract(ext, MemberKey(name, mname), member)
+		}
+	}
+	for _, enum := range objects(dump["Enums"]) {
+		name, _ := enum["Name"].(string)
+		extract(ext, EnumKey(name), enum)
+		for _, item := range objects(enum["Items"]) {
+			iname, _ := item["Name"].(string)
+			extract(ext, EnumItemKey(name, iname), item)
+		}
+	}
+	if b, err = json.Marshal(dump); err != nil {
+		return nil, nil, err
+	}
+	if root, err = rbxapijson.Decode(bytes.NewReader(b)); err != nil {
+		return nil, nil, err
+	}
+	return root, ext, nil
+}
+
+// objects returns the objects within a decoded JSON array.
+func objects(v interface{}) []map[string]interface{} {
+	list, _ := v.([]interface{})
+	objs := make([]map[string]interface{}, 0, len(list))
+	for _, v := range list {
+		if obj, ok := v.(map[string]interface{}); ok {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// stringList returns the strings within a decoded JSON array.
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	s := make([]string, 0, len(list))
+	for _, v := range list {
+		if v, ok := v.(string); ok {
+			s = append(s, v)
+		}
+	}
+	return s
+}
+
+// text returns a decoded JSON value as text. Values other than strings are
+// encoded as JSON.
+func text(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// extract moves the extended fields of the decoded element obj into ext.
+func extract(ext Root, key Key, obj map[string]interface{}) {
+	if v, ok := obj["ThreadSafety"]; ok {
+		ext.Set(key, "ThreadSafety", text(v))
+		delete(obj, "ThreadSafety")
+	}
+	switch v := obj["Capabilities"].(type) {
+	case []interface{}:
+		ext.Set(key, "Capabilities", stringList(v))
+	case map[string]interface{}:
+		// Properties have separate capabilities for reading and writing.
+		ext.Set(key, "ReadCapabilities", stringList(v["Read"]))
+		ext.Set(key, "WriteCapabilities", stringList(v["Write"]))
+	}
+	delete(obj, "Capabilities")
+	if tags, ok := obj["Tags"].([]interface{}); ok {
+		kept := make([]interface{}, 0, len(tags))
+		for _, tag := range tags {
+			tobj, ok := tag.(map[string]interface{})
+			if !ok {
+				kept = append(kept, tag)
+				continue
+			}
+			for name, value := range tobj {
+				ext.Set(key, TagPrefix+name, text(value))
+			}
+		}
+		obj["Tags"] = kept
+	}
+}
+
+// Encode encodes root and the extended fields of ext as an API dump in JSON
+// format, in the form read by Decode. If ext is empty, the dump is encoded
+// exactly as by rbxapijson.
+func Encode(w io.Writer, root *rbxapijson.Root, ext Root) error {
+	if len(ext) == 0 {
+		return rbxapijson.Encode(w, root)
+	}
+	var buf bytes.Buffer
+	if err := rbxapijson.Encode(&buf, root); err != nil {
+		return err
+	}
+	var dump map[string]interface{}
+	jd := json.NewDecoder(&buf)
+	jd.UseNumber()
+	if err := jd.Decode(&dump); err != nil {
+		return err
+	}
+	for _, class := range objects(dump["Classes"]) {
+		name, _ := class["Name"].(string)
+		insert(class, ext[ClassKey(name)])
+		for _, member := range objects(class["Members"]) {
+			mname, _ := member["Name"].(string)
+			insert(member, ext[MemberKey(name, mname)])
+		}
+	}
+	for _, enum := range objects(dump["Enums"]) {
+		name, _ := enum["Name"].(string)
+		insert(enum, ext[EnumKey(name)])
+		for _, item := range objects(enum["Items"]) {
+			iname, _ := item["Name"].(string)
+			insert(item, ext[EnumItemKey(name, iname)])
+		}
+	}
+	je := json.NewEncoder(w)
+	je.SetIndent("", "\t")
+	je.SetEscapeHTML(false)
+	return je.Encode(dump)
+}
+
+// insert adds fields to the decoded element obj. It is the inverse of extract.
+func insert(obj map[string]interface{}, fields Fields) {
+	if len(fields) == 0 {
+		return
+	}
+	if v, ok := fields["ThreadSafety"].(string); ok {
+		obj["ThreadSafety"] = v
+	}
+	if v, ok := fields["Capabilities"]; ok {
+		obj["Capabilities"] = v
+	} else if _, ok := fields["ReadCapabilities"]; ok {
+		obj["Capabilities"] = map[string]interface{}{
+			"Read":  fields["ReadCapabilities"],
+			"Write": fields["WriteCapabilities"],
+		}
+	}
+	tags, _ := obj["Tags"].([]interface{})
+	for _, name := range fields.AllNames() {
+		if strings.HasPrefix(name, TagPrefix) {
+			tags = append(tags, map[string]interface{}{
+				strings.TrimPrefix(name, TagPrefix): fields[name],
+			})
+		}
+	}
+	if len(tags) > 0 {
+		obj["Tags"] = tags
+	}
+}
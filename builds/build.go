@@ -1,11 +1,17 @@
 package builds
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/anaminus/but"
 	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
 	"github.com/robloxapi/rbxapiref/fetch"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +19,11 @@ type Build struct {
 	Config string
 	Info   Info
 	API    *rbxapijson.Root
+	// Ext holds the fields of API that are not represented by rbxapijson.
+	Ext apiext.Root
+	// Unreleased indicates that the build is newer than the current live
+	// build.
+	Unreleased bool
 }
 
 type Info struct {
@@ -45,14 +56,283 @@ type Settings struct {
 	// defined in the Configs setting. Builds from these configs are read
 	// sequentially.
 	UseConfigs []string
-	// DisableRewind sets whether rewinding is enabled. If true, builds that are
-	// not yet live will be included.
-	DisableRewind bool
+	// Channel is the name of the release channel formed by UseConfigs.
+	Channel string
+	// Channels maps the name of an additional release channel to a list of
+	// fetch configs, defined in the Configs setting. Each channel has a build
+	// history that is separate from that of UseConfigs.
+	Channels map[string][]string
+	// Rewind sets how builds newer than the current live build are handled.
+	Rewind RewindMode
+	// Metadata enables the tracking of changes to the reflection metadata of
+	// each build. Requires the reflection metadata of every build to be
+	// fetched.
+	Metadata bool
 }
 
+// ChannelNames returns the names of the additional release channels in
+// Channels, sorted by name.
+func (settings Settings) ChannelNames() []string {
+	names := make([]string, 0, len(settings.Channels))
+	for name := range settings.Channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fingerprint returns a hash of the JSON encoding of v.
+func fingerprint(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// ConfigFingerprint returns a fingerprint of the locations from which the
+// fetch config of the given name retrieves API dumps, which determine the
+// content of patches. Returns an empty string if the config does not exist.
+func (settings Settings) ConfigFingerprint(name string) string {
+	config, ok := settings.Configs[name]
+	if !ok {
+		return ""
+	}
+	return fingerprint(config.APIDump)
+}
+
+// MetadataFingerprint returns a fingerprint of the locations from which the
+// fetch config of the given name retrieves reflection metadata. Returns an
+// empty string if the config does not exist.
+func (settings Settings) MetadataFingerprint(name string) string {
+	config, ok := settings.Configs[name]
+	if !ok {
+		return ""
+	}
+	return fingerprint(config.ReflectionMetadata)
+}
+
+// Record describes the settings that produced a build history. Comparing the
+// record of a previous merge with the current settings determines which
+// cached patches are affected by changes to the settings.
+type Record struct {
+	// Configs maps the name of each fetch config to its fingerprints.
+	Configs    map[string]ConfigRecord
+	UseConfigs []string
+	Channel    string
+	Channels   map[string][]string
+	Rewind     RewindMode
+	Metadata   bool
+}
+
+// ConfigRecord holds the fingerprints of a fetch config.
+type ConfigRecord struct {
+	// API is the fingerprint returned by ConfigFingerprint.
+	API string
+	// Metadata is the fingerprint returned by MetadataFingerprint.
+	Metadata string
+}
+
+// Record returns a record of the settings.
+func (settings Settings) Record() Record {
+	record := Record{
+		Configs:    make(map[string]ConfigRecord, len(settings.Configs)),
+		UseConfigs: settings.UseConfigs,
+		Channel:    settings.Channel,
+		Channels:   settings.Channels,
+		Rewind:     settings.Rewind,
+		Metadata:   settings.Metadata,
+	}
+	for name := range settings.Configs {
+		record.Configs[name] = ConfigRecord{
+			API:      settings.ConfigFingerprint(name),
+			Metadata: settings.MetadataFingerprint(name),
+		}
+	}
+	return record
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Changes returns the names of the settings that differ between r and s,
+// sorted by name. A config or channel is named after its setting, such as
+// "Configs.Production".
+func (r Record) Changes(s Record) (fields []string) {
+	for name, config := range r.Configs {
+		if other, ok := s.Configs[name]; !ok || other != config {
+			fields = append(fields, "Configs."+name)
+		}
+	}
+	for name := range s.Configs {
+		if _, ok := r.Configs[name]; !ok {
+			fields = append(fields, "Configs."+name)
+		}
+	}
+	if !equalStrings(r.UseConfigs, s.UseConfigs) {
+		fields = append(fields, "UseConfigs")
+	}
+	if r.Channel != s.Channel {
+		fields = append(fields, "Channel")
+	}
+	for name, configs := range r.Channels {
+		if other, ok := s.Channels[name]; !ok || !equalStrings(other, configs) {
+			fields = append(fields, "Channels."+name)
+		}
+	}
+	for name := range s.Channels {
+		if _, ok := r.Channels[name]; !ok {
+			fields = append(fields, "Channels."+name)
+		}
+	}
+	if r.Rewind != s.Rewind {
+		fields = append(fields, "Rewind")
+	}
+	if r.Metadata != s.Metadata {
+		fields = append(fields, "Metadata")
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Invalidate returns the cached patches of a release channel that remain
+// valid under the current settings, given the record of the settings under
+// which they were merged. An empty channel refers to the channel formed by
+// UseConfigs.
+//
+// Only the settings that changed are considered. If the configs of the channel
+// changed, patches of configs no longer in the channel are dropped. If
+// rewinding changed to cut, unreleased patches are dropped. If metadata
+// tracking was disabled, recorded metadata is cleared. If the reflection
+// metadata locations of a config changed, the metadata of each patch of the
+// config, and of each patch that follows one, is cleared to be recorded again.
+//
+// Changes to the API dump locations of a config are detected per patch by
+// Merge. Changes to the build list and live locations only affect which builds
+// are merged.
+func (settings Settings) Invalidate(prev Record, channel string, cached []Patch) []Patch {
+	configs, prevConfigs := settings.UseConfigs, prev.UseConfigs
+	if channel != "" {
+		configs, prevConfigs = settings.Channels[channel], prev.Channels[channel]
+	}
+	var used map[string]bool
+	if !equalStrings(configs, prevConfigs) {
+		used = make(map[string]bool, len(configs))
+		for _, config := range configs {
+			used[config] = true
+		}
+	}
+	cut := settings.Rewind == RewindCut && prev.Rewind != RewindCut
+	patches := make([]Patch, 0, len(cached))
+	// Whether the metadata of the previous patch was retrieved from a location
+	// that changed.
+	var rebase bool
+	for _, patch := range cached {
+		if used != nil && !used[patch.Config] {
+			but.Log("STALE", patch.Info)
+			continue
+		}
+		if cut && patch.Unreleased {
+			but.Log("REWIND", patch.Info.Hash)
+			continue
+		}
+		if patch.HasMetadata {
+			switch {
+			case !settings.Metadata:
+				patch.HasMetadata = false
+			case !prev.Metadata:
+			case rebase, settings.MetadataFingerprint(patch.Config) != prev.Configs[patch.Config].Metadata:
+				// Metadata is relative to that of the previous build.
+				but.Log("STALE METADATA", patch.Info)
+				patch.HasMetadata = false
+			}
+			if !patch.HasMetadata {
+				patch.Metadata = nil
+				patch.Stale = true
+			}
+		}
+		rebase = settings.Metadata && prev.Metadata &&
+			settings.MetadataFingerprint(patch.Config) != prev.Configs[patch.Config].Metadata
+		patches = append(patches, patch)
+	}
+	return patches
+}
+
+// RewindMode specifies how builds that are newer than the current live build
+// are handled.
+type RewindMode int
+
+const (
+	// RewindCut excludes builds that are not yet live.
+	RewindCut RewindMode = iota
+	// RewindDisable includes builds that are not yet live, treating them as
+	// released.
+	RewindDisable
+	// RewindPreview includes builds that are not yet live, marking them as
+	// unreleased.
+	RewindPreview
+)
+
+var rewindModeStrings = [...]string{
+	RewindCut:     "Cut",
+	RewindDisable: "Disable",
+	RewindPreview: "Preview",
+}
+
+func (m RewindMode) String() string {
+	if m < 0 || int(m) >= len(rewindModeStrings) {
+		return "RewindMode(" + strconv.Itoa(int(m)) + ")"
+	}
+	return rewindModeStrings[m]
+}
+
+func (m RewindMode) MarshalText() (text []byte, err error) {
+	if m < 0 || int(m) >= len(rewindModeStrings) {
+		return nil, fmt.Errorf("invalid rewind mode %d", int(m))
+	}
+	return []byte(rewindModeStrings[m]), nil
+}
+
+func (m *RewindMode) UnmarshalText(text []byte) error {
+	for i, s := range rewindModeStrings {
+		if strings.EqualFold(s, string(text)) {
+			*m = RewindMode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown rewind mode %q", string(text))
+}
+
+// Fetch returns the list of builds from the configs in UseConfigs.
 func (settings Settings) Fetch() (builds []Build, err error) {
+	return settings.FetchConfigs(settings.UseConfigs)
+}
+
+// FetchChannel returns the list of builds from the configs of the given
+// release channel.
+func (settings Settings) FetchChannel(channel string) (builds []Build, err error) {
+	configs, ok := settings.Channels[channel]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %q", channel)
+	}
+	return settings.FetchConfigs(configs)
+}
+
+// FetchConfigs returns the list of builds from the logical concatenation of
+// the given configs, defined in the Configs setting.
+func (settings Settings) FetchConfigs(configs []string) (builds []Build, err error) {
 	client := &fetch.Client{CacheMode: fetch.CacheNone}
-	for _, cfg := range settings.UseConfigs {
+	for _, cfg := range configs {
 		client.Config = settings.Configs[cfg]
 		bs, err := client.Builds()
 		if err != nil {
@@ -75,7 +355,7 @@ func (settings Settings) Fetch() (builds []Build, err error) {
 	}
 	builds = b
 
-	if !settings.DisableRewind {
+	if settings.Rewind != RewindDisable {
 		// Rewind to current live build.
 		if lives, err := client.Live(); err != nil {
 			but.Logf("fetch live builds: %v\n", err)
@@ -90,10 +370,17 @@ func (settings Settings) Fetch() (builds []Build, err error) {
 				}
 			}
 			if max >= 0 {
-				for i := len(builds) - 1; i > max; i-- {
-					but.Log("REWIND", builds[i].Info.Hash)
+				if settings.Rewind == RewindPreview {
+					for i := max + 1; i < len(builds); i++ {
+						but.Log("PREVIEW", builds[i].Info.Hash)
+						builds[i].Unreleased = true
+					}
+				} else {
+					for i := len(builds) - 1; i > max; i-- {
+						but.Log("REWIND", builds[i].Info.Hash)
+					}
+					builds = builds[:max+1]
 				}
-				builds = builds[:max+1]
 			}
 		}
 	}
@@ -104,11 +391,33 @@ func (settings Settings) Fetch() (builds []Build, err error) {
 	return builds, nil
 }
 
-func (settings Settings) Merge(cached []Patch, builds []Build) (patches []Patch, err error) {
+// Merge produces a patch for each build, reusing the patches in cached when
+// they are still fresh, and fetching the API dump of each other build.
+//
+// If checkpoint is not nil, it is called after each fetched build with the
+// patches merged so far, followed by the cached patches of the builds not yet
+// merged. Passing the list to a later call to Merge resumes merging from where
+// it stopped.
+func (settings Settings) Merge(cached []Patch, builds []Build, checkpoint func([]Patch)) (patches []Patch, err error) {
 	client := &fetch.Client{CacheMode: fetch.CacheTemp}
 	var latest *Build
 loop:
 	for _, build := range builds {
+		if latest != nil {
+			// Check whether the build was folded into the latest patch.
+			last := &patches[len(patches)-1]
+			for _, patch := range cached {
+				if !patch.Info.Equal(last.Info) {
+					continue
+				}
+				for _, alias := range patch.Aliases {
+					if build.Info.Equal(alias) {
+						last.Aliases = append(last.Aliases, build.Info)
+						continue loop
+					}
+				}
+			}
+		}
 		for _, patch := range cached {
 			if !build.Info.Equal(patch.Info) {
 				// Not relevant; skip.
@@ -136,36 +445,87 @@ loop:
 					break
 				}
 			}
-			// Cached actions are still fresh; set them directly.
+			if patch.Config != build.Config {
+				// Build is now retrieved from a different config; actions are
+				// stale.
+				but.Log("STALE", patch.Info)
+				break
+			}
+			fp := settings.ConfigFingerprint(build.Config)
+			if patch.Source == nil {
+				// Patch predates provenance; assume that it matches the
+				// current config.
+				patch.Source = &Source{Fingerprint: fp}
+			} else if patch.Source.Fingerprint != fp {
+				// API dump locations of the config have changed since the
+				// patch was generated; actions may be stale.
+				but.Log("CHANGED", patch.Info)
+				break
+			}
+			// Cached actions are still fresh; set them directly. Aliases are
+			// added back as their builds are encountered.
+			patch.Aliases = nil
+			if patch.Unreleased && !build.Unreleased {
+				but.Log("PROMOTE", patch.Info)
+			}
+			patch.Unreleased = build.Unreleased
 			patches = append(patches, patch)
 			latest = &Build{Info: patch.Info, Config: patch.Config}
 			continue loop
 		}
 		but.Log("NEW", build.Info)
 		client.Config = settings.Configs[build.Config]
-		root, err := client.APIDump(build.Info.Hash)
+		root, ext, loc, err := client.APIDumpLocation(build.Info.Hash)
 		if but.IfErrorf(err, "%s: fetch build %s", build.Config, build.Info.Hash) {
 			continue
 		}
 		build.API = root
+		build.Ext = ext
+		source := &Source{
+			Location:    loc.URL.String(),
+			Time:        time.Now().UTC(),
+			Fingerprint: settings.ConfigFingerprint(build.Config),
+		}
 		var actions []Action
+		var discovered apiext.Root
 		if latest == nil {
 			// First build; compare with nothing.
-			actions = WrapActions((&rbxapijson.Diff{Prev: nil, Next: build.API}).Diff())
+			actions, _ = Compare(nil, nil, build.API, build.Ext)
 		} else {
 			if latest.API == nil {
 				// Previous build was cached; fetch its data to compare with
 				// current build.
 				client.Config = settings.Configs[latest.Config]
-				root, err := client.APIDump(latest.Info.Hash)
+				root, ext, err := client.APIDump(latest.Info.Hash)
 				if but.IfErrorf(err, "%s: fetch build %s", latest.Config, latest.Info.Hash) {
 					continue
 				}
 				latest.API = root
+				latest.Ext = ext
+			}
+			if ContentHash(latest.API, latest.Ext) == ContentHash(build.API, build.Ext) {
+				// Content is identical to previous build; fold into the
+				// previous patch.
+				but.Log("SAME", build.Info)
+				last := &patches[len(patches)-1]
+				last.Aliases = append(last.Aliases, build.Info)
+				last.Stale = true
+				if checkpoint != nil {
+					checkpoint(pending(patches, cached))
+				}
+				continue
 			}
-			actions = WrapActions((&rbxapijson.Diff{Prev: latest.API, Next: build.API}).Diff())
+			actions, discovered = Compare(latest.API, latest.Ext, build.API, build.Ext)
+		}
+		patch := Patch{
+			Stale:      true,
+			Info:       build.Info,
+			Config:     build.Config,
+			Unreleased: build.Unreleased,
+			Source:     source,
+			Actions:    actions,
+			Extension:  discovered,
 		}
-		patch := Patch{Stale: true, Info: build.Info, Config: build.Config, Actions: actions}
 		if latest != nil {
 			prev := latest.Info
 			patch.Prev = &prev
@@ -173,6 +533,9 @@ loop:
 		patches = append(patches, patch)
 		b := build
 		latest = &b
+		if checkpoint != nil {
+			checkpoint(pending(patches, cached))
+		}
 	}
 
 	// Set action indices.
@@ -180,7 +543,83 @@ loop:
 		for j := range patch.Actions {
 			patches[i].Actions[j].Index = j
 		}
+		for j := range patch.Metadata {
+			patches[i].Metadata[j].Index = j
+		}
 	}
 
 	return patches, nil
 }
+
+// pending returns a copy of patches, followed by each patch in cached of a
+// build that is newer than the build of the last patch.
+func pending(patches, cached []Patch) []Patch {
+	list := make([]Patch, len(patches), len(patches)+len(cached))
+	copy(list, patches)
+	if len(patches) == 0 {
+		return append(list, cached...)
+	}
+	last := patches[len(patches)-1].Info.Date
+	for _, patch := range cached {
+		if patch.Info.Date.After(last) {
+			list = append(list, patch)
+		}
+	}
+	return list
+}
+
+// MergeMetadata records the changes to the reflection metadata of each patch
+// that does not already have them recorded. Changes are relative to the
+// metadata of the build of the previous patch. Changes within builds folded
+// into a patch as aliases are attributed to the following patch. Patches whose
+// metadata could not be fetched are left unrecorded, to be retried by a later
+// merge.
+//
+// If checkpoint is not nil, it is called with patches after the metadata of
+// each patch is recorded.
+func (settings Settings) MergeMetadata(patches []Patch, checkpoint func([]Patch)) {
+	client := &fetch.Client{CacheMode: fetch.CacheTemp}
+	fetchMetadata := func(p *Patch) Metadata {
+		client.Config = settings.Configs[p.Config]
+		root, err := client.ReflectionMetadata(p.Info.Hash)
+		if but.IfErrorf(err, "%s: fetch metadata %s", p.Config, p.Info.Hash) {
+			return nil
+		}
+		return FlattenMetadata(root)
+	}
+
+	// Metadata of the build of the previous patch, or nil if unknown.
+	md := Metadata{}
+	for i := range patches {
+		patch := &patches[i]
+		if patch.HasMetadata {
+			if md != nil {
+				ReplayMetadata(md, patch.Metadata)
+			}
+			continue
+		}
+		if md == nil {
+			// Metadata of the previous build was not recorded; fetch it
+			// directly.
+			if md = fetchMetadata(&patches[i-1]); md == nil {
+				continue
+			}
+		}
+		next := fetchMetadata(patch)
+		if next == nil {
+			md = nil
+			continue
+		}
+		but.Log("METADATA", patch.Info)
+		patch.Metadata = CompareMetadata(md, next)
+		for j := range patch.Metadata {
+			patch.Metadata[j].Index = j
+		}
+		patch.HasMetadata = true
+		patch.Stale = true
+		md = next
+		if checkpoint != nil {
+			checkpoint(patches)
+		}
+	}
+}
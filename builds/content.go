@@ -0,0 +1,124 @@
+package builds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+)
+
+// ContentHash returns a hash of the content of an API dump. The dump is
+// normalized before hashing, so that dumps that differ only in the order of
+// classes, members, enums, items, or tags produce the same hash. The extended
+// fields of the dump are included.
+func ContentHash(root *rbxapijson.Root, ext apiext.Root) string {
+	h := sha256.New()
+	if err := rbxapijson.Encode(h, normalize(root)); err != nil {
+		// Writing to a hash never fails.
+		panic(err)
+	}
+	if len(ext) > 0 {
+		// Maps are encoded with sorted keys.
+		if err := json.NewEncoder(h).Encode(ext); err != nil {
+			panic(err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashPatch returns the SHA-256 hash of the JSON encoding of a patch. Two
+// patches have the same hash only if they are encoded identically. Fields that
+// are not encoded, such as Stale, do not affect the hash.
+func HashPatch(patch *Patch) (sum [sha256.Size]byte, err error) {
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(patch); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// normalize returns a copy of root with each list sorted by name. The original
+// root is not modified.
+func normalize(root *rbxapijson.Root) *rbxapijson.Root {
+	if root == nil {
+		return &rbxapijson.Root{}
+	}
+	norm := &rbxapijson.Root{
+		Classes: make([]*rbxapijson.Class, len(root.Classes)),
+		Enums:   make([]*rbxapijson.Enum, len(root.Enums)),
+	}
+	for i, class := range root.Classes {
+		c := *class
+		c.Tags = sortedTags(class.Tags)
+		c.Members = make([]rbxapi.Member, len(class.Members))
+		for j, member := range class.Members {
+			c.Members[j] = normalizeMember(member)
+		}
+		sort.Slice(c.Members, func(i, j int) bool {
+			a, b := c.Members[i], c.Members[j]
+			if a.GetName() == b.GetName() {
+				return a.GetMemberType() < b.GetMemberType()
+			}
+			return a.GetName() < b.GetName()
+		})
+		norm.Classes[i] = &c
+	}
+	sort.Slice(norm.Classes, func(i, j int) bool {
+		return norm.Classes[i].Name < norm.Classes[j].Name
+	})
+	for i, enum := range root.Enums {
+		e := *enum
+		e.Tags = sortedTags(enum.Tags)
+		e.Items = make([]*rbxapijson.EnumItem, len(enum.Items))
+		for j, item := range enum.Items {
+			it := *item
+			it.Tags = sortedTags(item.Tags)
+			e.Items[j] = &it
+		}
+		sort.Slice(e.Items, func(i, j int) bool {
+			return e.Items[i].Name < e.Items[j].Name
+		})
+		norm.Enums[i] = &e
+	}
+	sort.Slice(norm.Enums, func(i, j int) bool {
+		return norm.Enums[i].Name < norm.Enums[j].Name
+	})
+	return norm
+}
+
+func normalizeMember(member rbxapi.Member) rbxapi.Member {
+	switch member := member.(type) {
+	case *rbxapijson.Property:
+		m := *member
+		m.Tags = sortedTags(member.Tags)
+		return &m
+	case *rbxapijson.Function:
+		m := *member
+		m.Tags = sortedTags(member.Tags)
+		return &m
+	case *rbxapijson.Event:
+		m := *member
+		m.Tags = sortedTags(member.Tags)
+		return &m
+	case *rbxapijson.Callback:
+		m := *member
+		m.Tags = sortedTags(member.Tags)
+		return &m
+	}
+	return member
+}
+
+func sortedTags(tags rbxapijson.Tags) rbxapijson.Tags {
+	if len(tags) == 0 {
+		return nil
+	}
+	t := make(rbxapijson.Tags, len(tags))
+	copy(t, tags)
+	sort.Strings(t)
+	return t
+}
@@ -0,0 +1,48 @@
+package builds
+
+import (
+	"testing"
+
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/fetch"
+)
+
+func testPatch() Patch {
+	return Patch{
+		Info:   Info{Hash: "version-1", Version: fetch.Version{Major: 0, Minor: 1, Maint: 0, Build: 1}},
+		Config: "Production",
+		Actions: []Action{
+			{Type: patch.Add, Class: &rbxapijson.Class{Name: "Part", Tags: rbxapijson.Tags{}}},
+			{
+				Type:  patch.Change,
+				Class: &rbxapijson.Class{Name: "Part"},
+				Field: "MemoryCategory",
+				Prev:  &Value{V: "Instances"},
+				Next:  &Value{V: "PhysicsParts"},
+			},
+		},
+	}
+}
+
+func TestHashPatch(t *testing.T) {
+	a := testPatch()
+	b := testPatch()
+	ha, err := HashPatch(&a)
+	if err != nil {
+		t.Fatalf("hash patch: %v", err)
+	}
+	b.Stale = true
+	if hb, _ := HashPatch(&b); hb != ha {
+		t.Errorf("equal patches have different hashes")
+	}
+	b.Actions[1].Next = &Value{V: 2}
+	if hb, _ := HashPatch(&b); hb == ha {
+		t.Errorf("different patches have equal hashes")
+	}
+	b = testPatch()
+	b.Unreleased = true
+	if hb, _ := HashPatch(&b); hb == ha {
+		t.Errorf("patches with different flags have equal hashes")
+	}
+}
@@ -0,0 +1,154 @@
+package builds
+
+import (
+	"sort"
+
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+)
+
+// Diff contains the differences between the API of two arbitrary builds.
+type Diff struct {
+	// From is the build being compared from.
+	From Info
+	// To is the build being compared to.
+	To Info
+	// FromChannel and ToChannel are the names of the release channels of the
+	// compared builds, when comparing across channels.
+	FromChannel string `json:",omitempty"`
+	ToChannel   string `json:",omitempty"`
+	// Groups contains the actions that transform From into To, grouped by
+	// class or enum.
+	Groups []DiffGroup
+}
+
+// DiffGroup contains the actions that apply to a single class or enum,
+// including its members or items.
+type DiffGroup struct {
+	// Type is the type of the element, either "Class" or "Enum".
+	Type string
+	// Name is the name of the element.
+	Name string
+	// Actions is the list of actions applying to the element.
+	Actions []Action
+	// Metadata is the list of changes to the reflection metadata of the
+	// element.
+	Metadata []MetadataAction `json:",omitempty"`
+}
+
+// DiffPatches compares the reconstructed API of the builds of patches at
+// indices from and to. Each action is given an index that is unique within the
+// diff.
+func DiffPatches(patches []Patch, from, to int) Diff {
+	diff := newDiff(
+		patches[from].Info, Snapshot(patches, from), SnapshotExtension(patches, from),
+		patches[to].Info, Snapshot(patches, to), SnapshotExtension(patches, to),
+	)
+	diff.addMetadata(SnapshotMetadata(patches, from), SnapshotMetadata(patches, to))
+	return diff
+}
+
+// DiffChannels compares the reconstructed API of the latest build of two
+// release channels. Each list of patches must not be empty.
+func DiffChannels(fromName string, from []Patch, toName string, to []Patch) Diff {
+	diff := newDiff(
+		from[len(from)-1].Info, Snapshot(from, len(from)-1), SnapshotExtension(from, len(from)-1),
+		to[len(to)-1].Info, Snapshot(to, len(to)-1), SnapshotExtension(to, len(to)-1),
+	)
+	diff.addMetadata(
+		SnapshotMetadata(from, len(from)-1),
+		SnapshotMetadata(to, len(to)-1),
+	)
+	diff.FromChannel = fromName
+	diff.ToChannel = toName
+	return diff
+}
+
+func newDiff(from Info, prev *rbxapijson.Root, prevExt apiext.Root, to Info, next *rbxapijson.Root, nextExt apiext.Root) Diff {
+	// Fields reported by only one side cannot be compared, so discovered
+	// fields are ignored.
+	actions, _ := Compare(prev, prevExt, next, nextExt)
+	for i := range actions {
+		actions[i].Index = i
+	}
+	return Diff{
+		From:   from,
+		To:     to,
+		Groups: GroupActions(actions),
+	}
+}
+
+// addMetadata adds the changes between two versions of reflection metadata to
+// the groups of the diff. Does nothing if either version is unknown.
+func (diff *Diff) addMetadata(prev, next Metadata) {
+	if prev == nil || next == nil {
+		return
+	}
+	actions := CompareMetadata(prev, next)
+	if len(actions) == 0 {
+		return
+	}
+	lookup := map[[2]string]int{}
+	for i, group := range diff.Groups {
+		lookup[[2]string{group.Type, group.Name}] = i
+	}
+	for i, action := range actions {
+		action.Index = i
+		key := [2]string{"Class", action.Element.Name}
+		switch action.Element.Type {
+		case "Member":
+			key[1] = action.Element.Parent
+		case "Enum":
+			key[0] = "Enum"
+		case "EnumItem":
+			key = [2]string{"Enum", action.Element.Parent}
+		}
+		j, ok := lookup[key]
+		if !ok {
+			j = len(diff.Groups)
+			lookup[key] = j
+			diff.Groups = append(diff.Groups, DiffGroup{Type: key[0], Name: key[1]})
+		}
+		diff.Groups[j].Metadata = append(diff.Groups[j].Metadata, action)
+	}
+	sortGroups(diff.Groups)
+}
+
+// GroupActions groups a list of actions by the class or enum to which each
+// action applies. Class groups are sorted by name, followed by enum groups
+// sorted by name. The order of actions within each group is preserved.
+func GroupActions(actions []Action) []DiffGroup {
+	var groups []DiffGroup
+	lookup := map[[2]string]int{}
+	for _, action := range actions {
+		var key [2]string
+		switch {
+		case action.Class != nil:
+			key = [2]string{"Class", action.Class.Name}
+		case action.Enum != nil:
+			key = [2]string{"Enum", action.Enum.Name}
+		default:
+			continue
+		}
+		i, ok := lookup[key]
+		if !ok {
+			i = len(groups)
+			lookup[key] = i
+			groups = append(groups, DiffGroup{Type: key[0], Name: key[1]})
+		}
+		groups[i].Actions = append(groups[i].Actions, action)
+	}
+	sortGroups(groups)
+	return groups
+}
+
+// sortGroups sorts class groups by name, followed by enum groups sorted by
+// name.
+func sortGroups(groups []DiffGroup) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Type != groups[j].Type {
+			return groups[i].Type == "Class"
+		}
+		return groups[i].Name < groups[j].Name
+	})
+}
@@ -0,0 +1,215 @@
+package builds
+
+import (
+	"sort"
+
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+)
+
+// ExtensionKey returns the key of the element the action applies to.
+func (a *Action) ExtensionKey() apiext.Key {
+	switch {
+	case a.Class != nil && a.GetMember() != nil:
+		return apiext.MemberKey(a.Class.Name, a.GetMember().GetName())
+	case a.Class != nil:
+		return apiext.ClassKey(a.Class.Name)
+	case a.Enum != nil && a.EnumItem != nil:
+		return apiext.EnumItemKey(a.Enum.Name, a.EnumItem.Name)
+	case a.Enum != nil:
+		return apiext.EnumKey(a.Enum.Name)
+	}
+	return apiext.Key{}
+}
+
+// IsExtension returns whether the action is a change to an extended field.
+func (a *Action) IsExtension() bool {
+	return a.Type == patch.Change && apiext.IsField(a.Field)
+}
+
+// Compare returns the actions that transform prev into next. Each Add action
+// carries the extended fields of the elements it adds. Changes to the extended
+// fields of elements present in both builds are included as Change actions.
+//
+// An extended field that prev does not report for any element, such as when
+// prev predates the field, is returned in discovered rather than as a change.
+// Otherwise, an element that gains or loses a field produces a Change action
+// with a nil Prev or Next, respectively.
+func Compare(prev *rbxapijson.Root, prevExt apiext.Root, next *rbxapijson.Root, nextExt apiext.Root) (actions []Action, discovered apiext.Root) {
+	actions = WrapActions((&rbxapijson.Diff{Prev: prev, Next: next}).Diff())
+	for i := range actions {
+		action := &actions[i]
+		if action.Type != patch.Add {
+			continue
+		}
+		switch {
+		case action.Class != nil:
+			action.Extension = nextExt.Select(action.Class.Name, action.GetElement())
+		case action.Enum != nil:
+			action.Extension = nextExt.Select(action.Enum.Name, action.GetElement())
+		}
+	}
+
+	// Names of the fields reported by prev for at least one element.
+	known := map[string]bool{}
+	for _, fields := range prevExt {
+		for name := range fields {
+			known[name] = true
+		}
+	}
+
+	keys := nextExt.Keys()
+	for _, key := range prevExt.Keys() {
+		if nextExt[key] == nil {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		p, n := prevExt[key], nextExt[key]
+		change, ok := elementAction(prev, key)
+		if !ok {
+			continue
+		}
+		if _, ok := elementAction(next, key); !ok {
+			continue
+		}
+		names := make([]string, 0, len(p)+len(n))
+		for name := range p {
+			if _, ok := n[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		for name := range n {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			pv, pok := p[name]
+			nv, nok := n[name]
+			switch {
+			case pok && nok && apiext.Equal(pv, nv):
+				continue
+			case pok || known[name]:
+				action := change
+				action.Field = name
+				if pok {
+					action.Prev = WrapValue(pv)
+				}
+				if nok {
+					action.Next = WrapValue(nv)
+				}
+				actions = append(actions, action)
+			default:
+				if discovered == nil {
+					discovered = apiext.Root{}
+				}
+				if discovered[key] == nil {
+					discovered[key] = apiext.Fields{}
+				}
+				discovered[key][name] = nv
+			}
+		}
+	}
+	return actions, discovered
+}
+
+// elementAction returns a Change action without a field that applies to the
+// element of root identified by key. ok is false if root has no such element.
+func elementAction(root *rbxapijson.Root, key apiext.Key) (action Action, ok bool) {
+	if root == nil {
+		return action, false
+	}
+	action.Type = patch.Change
+	switch key.Type {
+	case "Class", "Member":
+		name := key.Name
+		if key.Type == "Member" {
+			name = key.Parent
+		}
+		class, _ := root.GetClass(name).(*rbxapijson.Class)
+		if class == nil {
+			return action, false
+		}
+		members := class.Members
+		class.Members = nil
+		action.Class = class.Copy().(*rbxapijson.Class)
+		class.Members = members
+		if key.Type == "Member" {
+			member := class.GetMember(key.Name)
+			if member == nil {
+				return action, false
+			}
+			action.SetMember(member.Copy())
+		}
+	case "Enum", "EnumItem":
+		name := key.Name
+		if key.Type == "EnumItem" {
+			name = key.Parent
+		}
+		enum, _ := root.GetEnum(name).(*rbxapijson.Enum)
+		if enum == nil {
+			return action, false
+		}
+		items := enum.Items
+		enum.Items = nil
+		action.Enum = enum.Copy().(*rbxapijson.Enum)
+		enum.Items = items
+		if key.Type == "EnumItem" {
+			item, _ := enum.GetEnumItem(key.Name).(*rbxapijson.EnumItem)
+			if item == nil {
+				return action, false
+			}
+			action.EnumItem = item.Copy().(*rbxapijson.EnumItem)
+		}
+	default:
+		return action, false
+	}
+	return action, true
+}
+
+// ReplayExtension applies the changes to extended fields recorded by a patch
+// to ext.
+func ReplayExtension(ext apiext.Root, p *Patch) {
+	for i := range p.Actions {
+		action := &p.Actions[i]
+		switch action.Type {
+		case patch.Add:
+			for key, fields := range action.Extension {
+				ext[key] = fields.Copy()
+			}
+		case patch.Remove:
+			key := action.ExtensionKey()
+			delete(ext, key)
+			// Members and items are removed along with their parent.
+			child := map[string]string{"Class": "Member", "Enum": "EnumItem"}[key.Type]
+			for k := range ext {
+				if k.Type == child && k.Parent == key.Name {
+					delete(ext, k)
+				}
+			}
+		case patch.Change:
+			if action.IsExtension() {
+				ext.Set(action.ExtensionKey(), action.Field, action.GetNext())
+			}
+		}
+	}
+	for key, fields := range p.Extension {
+		for name, value := range fields {
+			ext.Set(key, name, value)
+		}
+	}
+}
+
+// SnapshotExtension reconstructs the extended fields of the API as they were
+// at the build of the patch at index i.
+func SnapshotExtension(patches []Patch, i int) apiext.Root {
+	ext := apiext.Root{}
+	if i >= len(patches) {
+		i = len(patches) - 1
+	}
+	for j := range patches[:i+1] {
+		ReplayExtension(ext, &patches[j])
+	}
+	return ext
+}
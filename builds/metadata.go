@@ -0,0 +1,232 @@
+package builds
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxfile"
+)
+
+// MetadataElement identifies an element described by reflection metadata.
+type MetadataElement struct {
+	// Type is the type of the element: "Class", "Member", "Enum", or
+	// "EnumItem".
+	Type string
+	// Parent is the name of the class of a member, or the enum of an enum
+	// item. Empty for classes and enums.
+	Parent string `json:",omitempty"`
+	// Name is the name of the element.
+	Name string
+}
+
+// String returns the full name of the element. Members and enum items are
+// qualified by the name of their parent.
+func (e MetadataElement) String() string {
+	if e.Parent != "" {
+		return e.Parent + "." + e.Name
+	}
+	return e.Name
+}
+
+// metadataTypes maps the type of an element to its position when sorting.
+// Members are sorted after their class, and items after their enum.
+var metadataTypes = map[string]int{
+	"Class":    0,
+	"Member":   1,
+	"Enum":     2,
+	"EnumItem": 3,
+}
+
+// less returns whether e is sorted before f. Elements are grouped by class or
+// enum, with classes before enums.
+func (e MetadataElement) less(f MetadataElement) bool {
+	et, ft := metadataTypes[e.Type], metadataTypes[f.Type]
+	if et/2 != ft/2 {
+		return et < ft
+	}
+	en, fn := e.Name, f.Name
+	if e.Parent != "" {
+		en = e.Parent
+	}
+	if f.Parent != "" {
+		fn = f.Parent
+	}
+	if en != fn {
+		return en < fn
+	}
+	if et != ft {
+		return et < ft
+	}
+	return e.Name < f.Name
+}
+
+// Metadata is a flattened form of the reflection metadata of a build. It maps
+// each element to its properties, each of which is represented as a string.
+type Metadata map[MetadataElement]map[string]string
+
+// FlattenMetadata converts reflection metadata into its flattened form.
+// Instances that do not describe an element are ignored. The Name property,
+// which identifies the element, is excluded.
+func FlattenMetadata(root *rbxfile.Root) Metadata {
+	md := Metadata{}
+	add := func(element MetadataElement, inst *rbxfile.Instance) {
+		props := make(map[string]string, len(inst.Properties))
+		for name, value := range inst.Properties {
+			if name == "Name" || value == nil {
+				continue
+			}
+			props[name] = value.String()
+		}
+		md[element] = props
+	}
+	if root == nil {
+		return md
+	}
+	for _, list := range root.Instances {
+		switch list.ClassName {
+		case "ReflectionMetadataClasses":
+			for _, class := range list.Children {
+				if class.ClassName != "ReflectionMetadataClass" {
+					continue
+				}
+				add(MetadataElement{Type: "Class", Name: class.Name()}, class)
+				for _, memberTypeList := range class.Children {
+					for _, member := range memberTypeList.Children {
+						if member.ClassName != "ReflectionMetadataMember" {
+							continue
+						}
+						add(MetadataElement{Type: "Member", Parent: class.Name(), Name: member.Name()}, member)
+					}
+				}
+			}
+		case "ReflectionMetadataEnums":
+			for _, enum := range list.Children {
+				if enum.ClassName != "ReflectionMetadataEnum" {
+					continue
+				}
+				add(MetadataElement{Type: "Enum", Name: enum.Name()}, enum)
+				for _, item := range enum.Children {
+					if item.ClassName != "ReflectionMetadataEnumItem" {
+						continue
+					}
+					add(MetadataElement{Type: "EnumItem", Parent: enum.Name(), Name: item.Name()}, item)
+				}
+			}
+		}
+	}
+	return md
+}
+
+// MetadataAction describes a change to a single property of the reflection
+// metadata of an element.
+type MetadataAction struct {
+	// Type is Add when the property is set, Remove when the property is
+	// unset, and Change when the value of the property changes.
+	Type    patch.Type
+	Index   int `json:"-"`
+	Element MetadataElement
+	Field   string
+	Prev    string `json:",omitempty"`
+	Next    string `json:",omitempty"`
+}
+
+func (a *MetadataAction) String() string {
+	name := a.Element.Type + " " + a.Element.String()
+	switch a.Type {
+	case patch.Add:
+		return a.Type.String() + " metadata " + a.Field + " of " + name +
+			" as " + strconv.Quote(a.Next)
+	case patch.Remove:
+		return a.Type.String() + " metadata " + a.Field + " of " + name
+	}
+	return a.Type.String() + " metadata " + a.Field + " of " + name +
+		" from " + strconv.Quote(a.Prev) +
+		" to " + strconv.Quote(a.Next)
+}
+
+// CompareMetadata returns the actions that transform prev into next. Actions
+// are sorted by element, then by field.
+func CompareMetadata(prev, next Metadata) []MetadataAction {
+	elements := make([]MetadataElement, 0, len(next))
+	for element := range prev {
+		if _, ok := next[element]; !ok {
+			elements = append(elements, element)
+		}
+	}
+	for element := range next {
+		elements = append(elements, element)
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		return elements[i].less(elements[j])
+	})
+
+	var actions []MetadataAction
+	for _, element := range elements {
+		p, n := prev[element], next[element]
+		fields := make([]string, 0, len(n))
+		for field := range p {
+			if _, ok := n[field]; !ok {
+				fields = append(fields, field)
+			}
+		}
+		for field := range n {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			pv, pok := p[field]
+			nv, nok := n[field]
+			action := MetadataAction{Element: element, Field: field, Prev: pv, Next: nv}
+			switch {
+			case !pok:
+				action.Type = patch.Add
+			case !nok:
+				action.Type = patch.Remove
+			case pv != nv:
+				action.Type = patch.Change
+			default:
+				continue
+			}
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// ReplayMetadata applies a list of metadata actions to md.
+func ReplayMetadata(md Metadata, actions []MetadataAction) {
+	for _, action := range actions {
+		props := md[action.Element]
+		switch action.Type {
+		case patch.Add, patch.Change:
+			if props == nil {
+				props = map[string]string{}
+				md[action.Element] = props
+			}
+			props[action.Field] = action.Next
+		case patch.Remove:
+			delete(props, action.Field)
+			if len(props) == 0 {
+				delete(md, action.Element)
+			}
+		}
+	}
+}
+
+// SnapshotMetadata reconstructs the reflection metadata as it was at the build
+// of the patch at index i. Returns nil if the metadata of any patch up to and
+// including patches[i] was not recorded.
+func SnapshotMetadata(patches []Patch, i int) Metadata {
+	md := Metadata{}
+	if i >= len(patches) {
+		i = len(patches) - 1
+	}
+	for _, p := range patches[:i+1] {
+		if !p.HasMetadata {
+			return nil
+		}
+		ReplayMetadata(md, p.Metadata)
+	}
+	return md
+}
@@ -2,26 +2,83 @@ package builds
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/robloxapi/rbxapi"
 	"github.com/robloxapi/rbxapi/patch"
 	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
 	"reflect"
 )
 
 type Patch struct {
-	Stale   bool  `json:"-"`
-	Prev    *Info `json:",omitempty"`
-	Info    Info
+	Stale bool  `json:"-"`
+	Prev  *Info `json:",omitempty"`
+	Info  Info
+	// Aliases lists subsequent builds whose API content is identical to the
+	// build of the patch. Such builds are folded into the patch rather than
+	// producing empty patches.
+	Aliases []Info `json:",omitempty"`
 	Config  string
+	// Unreleased indicates that the build of the patch was not yet live when
+	// the patch was generated. Such patches are retained only when rewinding
+	// is set to preview, and are promoted once the build becomes live.
+	Unreleased bool `json:",omitempty"`
+	// Source records where the API dump of the build was retrieved from. Nil
+	// for patches that predate provenance.
+	Source  *Source `json:",omitempty"`
 	Actions []Action
+	// HasMetadata indicates that changes to the reflection metadata of the
+	// build are recorded. Only set when metadata tracking is enabled.
+	HasMetadata bool `json:",omitempty"`
+	// Metadata lists the changes to the reflection metadata of the build,
+	// relative to the build of the previous patch.
+	Metadata []MetadataAction `json:",omitempty"`
+	// Extension lists extended fields of existing elements that became known
+	// in the build, because the build of the previous patch predates them. A
+	// nil value indicates a field that is no longer reported.
+	Extension apiext.Root `json:",omitempty"`
+}
+
+// Source describes the provenance of the API dump of a patch.
+type Source struct {
+	// Location is the URL from which the API dump was retrieved. Empty if
+	// unknown.
+	Location string `json:",omitempty"`
+	// Time is when the API dump was retrieved. Zero if unknown.
+	Time time.Time
+	// Fingerprint is the fingerprint of the API dump locations of the fetch
+	// config of the patch at the time the patch was generated, as returned by
+	// Settings.ConfigFingerprint.
+	Fingerprint string
+}
+
+// Covers returns whether info refers to the build of the patch, or to one of
+// its aliases.
+func (p *Patch) Covers(info Info) bool {
+	if p.Info.Equal(info) {
+		return true
+	}
+	for _, alias := range p.Aliases {
+		if alias.Equal(info) {
+			return true
+		}
+	}
+	return false
 }
 
 func MergePatches(left, right []Patch, filter func(*Action) bool) []Patch {
 	var patches []Patch
 	for _, l := range left {
 		patch := Patch{
-			Info:    l.Info,
-			Actions: make([]Action, len(l.Actions)),
+			Info:       l.Info,
+			Unreleased: l.Unreleased,
+			Actions:    make([]Action, len(l.Actions)),
+			Metadata:   append([]MetadataAction(nil), l.Metadata...),
+			Extension:  l.Extension.Copy(),
 		}
 		copy(patch.Actions, l.Actions)
 		patches = append(patches, patch)
@@ -39,12 +96,22 @@ loop:
 						}
 					}
 				}
+				patches[p].Metadata = append(patches[p].Metadata, r.Metadata...)
+				for key, fields := range r.Extension {
+					if patches[p].Extension == nil {
+						patches[p].Extension = apiext.Root{}
+					}
+					patches[p].Extension[key] = fields.Copy()
+				}
 				continue loop
 			}
 		}
 		patch := Patch{
-			Info:    r.Info,
-			Actions: make([]Action, len(r.Actions)),
+			Info:       r.Info,
+			Unreleased: r.Unreleased,
+			Actions:    make([]Action, len(r.Actions)),
+			Metadata:   append([]MetadataAction(nil), r.Metadata...),
+			Extension:  r.Extension.Copy(),
 		}
 		if filter == nil {
 			copy(patch.Actions, r.Actions)
@@ -105,6 +172,10 @@ type Action struct {
 	Field    string               `json:",omitempty"`
 	Prev     *Value               `json:",omitempty"`
 	Next     *Value               `json:",omitempty"`
+	// Extension holds the extended fields of the elements added by an Add
+	// action, including the members of an added class, and the items of an
+	// added enum.
+	Extension apiext.Root `json:",omitempty"`
 }
 
 func WrapActions(actions []patch.Action) []Action {
@@ -231,7 +302,31 @@ func (a *Action) GetNext() interface{} {
 	}
 	return nil
 }
-func (a *Action) String() string { return "Action" }
+func (a *Action) String() string {
+	name := a.GetElementType() + " " + a.ElementName()
+	if a.Type == patch.Change {
+		return a.Type.String() + " " + a.Field + " of " + name +
+			" from " + a.Prev.String() +
+			" to " + a.Next.String()
+	}
+	return a.Type.String() + " " + name
+}
+
+// ElementName returns the full name of the element the action applies to.
+// Members and enum items are qualified by the name of their parent.
+func (a *Action) ElementName() string {
+	switch {
+	case a.Class != nil && a.GetMember() != nil:
+		return a.Class.Name + "." + a.GetMember().GetName()
+	case a.Class != nil:
+		return a.Class.Name
+	case a.Enum != nil && a.EnumItem != nil:
+		return a.Enum.Name + "." + a.EnumItem.Name
+	case a.Enum != nil:
+		return a.Enum.Name
+	}
+	return ""
+}
 func (a *Action) GetElementType() string {
 	switch {
 	case a.Class != nil && a.GetMember() != nil:
@@ -294,6 +389,37 @@ func WrapValue(v interface{}) *Value {
 	return &w
 }
 
+// String returns a string representation of the value.
+func (v *Value) String() string {
+	if v == nil {
+		return "<nil>"
+	}
+	switch v := v.V.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return strconv.Quote(v)
+	case rbxapijson.Type:
+		return v.String()
+	case []string:
+		return "[" + strings.Join(v, ", ") + "]"
+	case rbxapijson.Parameters:
+		n := v.GetLength()
+		ss := make([]string, n)
+		for i := 0; i < n; i++ {
+			param := v.GetParameter(i).(rbxapijson.Parameter)
+			ss[i] = param.Type.String() + " " + param.Name
+			if param.HasDefault {
+				ss[i] += " = " + param.Default
+			}
+		}
+		return "(" + strings.Join(ss, ", ") + ")"
+	}
+	return fmt.Sprint(v.V)
+}
+
 func (v *Value) MarshalJSON() (b []byte, err error) {
 	var w struct {
 		Type  string
@@ -376,8 +502,9 @@ func MakeSubactions(action Action) []Action {
 		actions := make([]Action, len(class.Members))
 		for i, member := range class.Members {
 			actions[i] = Action{
-				Type:  action.GetType(),
-				Class: class,
+				Type:      action.GetType(),
+				Class:     class,
+				Extension: action.Extension.Select(class.Name, member),
 			}
 			actions[i].SetMember(member)
 		}
@@ -386,9 +513,10 @@ func MakeSubactions(action Action) []Action {
 		actions := make([]Action, len(enum.Items))
 		for i, item := range enum.Items {
 			actions[i] = Action{
-				Type:     action.GetType(),
-				Enum:     enum,
-				EnumItem: item,
+				Type:      action.GetType(),
+				Enum:      enum,
+				EnumItem:  item,
+				Extension: action.Extension.Select(enum.Name, item),
 			}
 		}
 		return actions
@@ -0,0 +1,221 @@
+package builds
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+)
+
+// RefKind indicates the kind of element referred to by a Ref.
+type RefKind int
+
+const (
+	// RefAny refers to a class or enum, along with an optional member or
+	// item.
+	RefAny RefKind = iota
+	// RefClass refers to a class, along with an optional member.
+	RefClass
+	// RefEnum refers to an enum, along with an optional item.
+	RefEnum
+	// RefType refers to a value type. Matches members that use the type.
+	RefType
+)
+
+// Ref refers to an API element within a list of actions.
+type Ref struct {
+	Kind RefKind
+	// Primary is the name of a class, enum, or type.
+	Primary string
+	// Secondary is the name of a member or enum item. May be empty.
+	Secondary string
+	// Field is the name of a field. If not empty, only Change actions of the
+	// field are matched, along with actions that add or remove the element.
+	// The name is case-insensitive, and also matches fields that have it as a
+	// suffix, so that "Security" matches both "ReadSecurity" and
+	// "WriteSecurity".
+	Field string
+}
+
+// ParseRef parses a reference to an API element. A reference has the form
+// "[kind:]Primary[.Secondary]", where kind is one of "class", "enum", or
+// "type". Primary and Secondary may also be separated by ":" or "/". For
+// example:
+//
+//	Humanoid:LoadAnimation
+//	Workspace.StreamingEnabled
+//	enum:Material/Plastic
+//	type:Vector3
+//
+// A reference without a kind refers to either a class or an enum.
+func ParseRef(s, field string) (ref Ref, err error) {
+	ref.Field = strings.TrimSpace(field)
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, ":"); i >= 0 {
+		switch strings.ToLower(s[:i]) {
+		case "class":
+			ref.Kind = RefClass
+			s = s[i+1:]
+		case "enum":
+			ref.Kind = RefEnum
+			s = s[i+1:]
+		case "type":
+			ref.Kind = RefType
+			s = s[i+1:]
+		}
+	}
+	if i := strings.IndexAny(s, ".:/"); i >= 0 {
+		ref.Primary, ref.Secondary = s[:i], s[i+1:]
+	} else {
+		ref.Primary = s
+	}
+	if ref.Primary == "" {
+		return ref, fmt.Errorf("reference %q has no name", s)
+	}
+	if ref.Kind == RefType && ref.Secondary != "" {
+		return ref, fmt.Errorf("type reference %q cannot have a member", s)
+	}
+	return ref, nil
+}
+
+// String returns the reference in the form parsed by ParseRef.
+func (ref Ref) String() string {
+	var s string
+	switch ref.Kind {
+	case RefClass:
+		s = "class:"
+	case RefEnum:
+		s = "enum:"
+	case RefType:
+		s = "type:"
+	}
+	s += ref.Primary
+	if ref.Secondary != "" {
+		s += "." + ref.Secondary
+	}
+	return s
+}
+
+// Match returns whether the action touches the element referred to by ref.
+// Adding or removing a class or enum matches references to its members or
+// items.
+func (ref Ref) Match(action *Action) bool {
+	if ref.Field != "" && action.Type == patch.Change && !ref.matchField(action.Field) {
+		return false
+	}
+	switch ref.Kind {
+	case RefType:
+		return ref.matchType(action)
+	case RefClass:
+		return ref.matchClass(action)
+	case RefEnum:
+		return ref.matchEnum(action)
+	}
+	return ref.matchClass(action) || ref.matchEnum(action)
+}
+
+func (ref Ref) matchField(field string) bool {
+	field = strings.ToLower(field)
+	return strings.HasSuffix(field, strings.ToLower(ref.Field))
+}
+
+func (ref Ref) matchClass(action *Action) bool {
+	if action.Class == nil || action.Class.Name != ref.Primary {
+		return false
+	}
+	if ref.Secondary == "" {
+		// Member actions do not touch the class itself.
+		return action.GetMember() == nil
+	}
+	if member := action.GetMember(); member != nil {
+		return member.GetName() == ref.Secondary
+	}
+	if action.Type == patch.Change {
+		return false
+	}
+	// Entire class is added or removed, including the member.
+	for _, member := range action.Class.Members {
+		if member.GetName() == ref.Secondary {
+			return true
+		}
+	}
+	return false
+}
+
+func (ref Ref) matchEnum(action *Action) bool {
+	if action.Enum == nil || action.Enum.Name != ref.Primary {
+		return false
+	}
+	if ref.Secondary == "" {
+		return action.EnumItem == nil
+	}
+	if action.EnumItem != nil {
+		return action.EnumItem.Name == ref.Secondary
+	}
+	if action.Type == patch.Change {
+		return false
+	}
+	for _, item := range action.Enum.Items {
+		if item.Name == ref.Secondary {
+			return true
+		}
+	}
+	return false
+}
+
+func (ref Ref) matchType(action *Action) bool {
+	if action.Type == patch.Change {
+		return ref.valueHasType(action.Prev) || ref.valueHasType(action.Next)
+	}
+	if member := action.GetMember(); member != nil {
+		return ref.memberHasType(member)
+	}
+	if action.Class != nil {
+		for _, member := range action.Class.Members {
+			if ref.memberHasType(member) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (ref Ref) valueHasType(v *Value) bool {
+	if v == nil {
+		return false
+	}
+	switch v := v.V.(type) {
+	case rbxapijson.Type:
+		return v.Name == ref.Primary
+	case rbxapijson.Parameters:
+		if v.List != nil {
+			return ref.paramsHaveType(*v.List)
+		}
+	}
+	return false
+}
+
+func (ref Ref) paramsHaveType(params []rbxapijson.Parameter) bool {
+	for _, param := range params {
+		if param.Type.Name == ref.Primary {
+			return true
+		}
+	}
+	return false
+}
+
+func (ref Ref) memberHasType(member rbxapi.Member) bool {
+	switch member := member.(type) {
+	case *rbxapijson.Property:
+		return member.ValueType.Name == ref.Primary
+	case *rbxapijson.Function:
+		return member.ReturnType.Name == ref.Primary || ref.paramsHaveType(member.Parameters)
+	case *rbxapijson.Event:
+		return ref.paramsHaveType(member.Parameters)
+	case *rbxapijson.Callback:
+		return member.ReturnType.Name == ref.Primary || ref.paramsHaveType(member.Parameters)
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package builds
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+)
+
+// Snapshot reconstructs the API as it was at the build of the patch at index
+// i, by replaying the actions of each patch from the first up to and including
+// patches[i]. Returns an empty root if i is less than 0.
+func Snapshot(patches []Patch, i int) *rbxapijson.Root {
+	root := &rbxapijson.Root{}
+	if i < 0 {
+		return root
+	}
+	if i >= len(patches) {
+		i = len(patches) - 1
+	}
+	for _, p := range patches[:i+1] {
+		Replay(root, p.Actions)
+	}
+	return root
+}
+
+// Replay applies a list of actions to root.
+func Replay(root *rbxapijson.Root, actions []Action) {
+	for i := range actions {
+		root.Patch([]patch.Action{&actions[i]})
+	}
+}
+
+// dateLayouts are the layouts of dates accepted by FindPatch.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// FindPatch returns the index of the patch referred to by ref. The reference
+// may be the hash of a build, including a build folded into a patch as an
+// alias, a version of the form "0.123.0.456", or a date
+// of the form "2006-01-02", optionally followed by a time. A date refers to
+// the latest build released at or before the date. A date without a time
+// includes the entire day. Returns an error if no patch matches, or if more
+// than one patch matches.
+func FindPatch(patches []Patch, ref string) (index int, err error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return -1, fmt.Errorf("empty build reference")
+	}
+	for _, layout := range dateLayouts {
+		date, err := time.Parse(layout, ref)
+		if err != nil {
+			continue
+		}
+		if len(ref) == len("2006-01-02") {
+			date = date.AddDate(0, 0, 1).Add(-1)
+		}
+		index = -1
+		for i, p := range patches {
+			if !p.Info.Date.After(date) {
+				index = i
+			}
+		}
+		if index < 0 {
+			return -1, fmt.Errorf("no build at or before %s", ref)
+		}
+		return index, nil
+	}
+	index = -1
+	for i, p := range patches {
+		if !matchInfo(p.Info, ref) && !matchAliases(p.Aliases, ref) {
+			continue
+		}
+		if index >= 0 {
+			return -1, fmt.Errorf("build reference %q is ambiguous", ref)
+		}
+		index = i
+	}
+	if index < 0 {
+		return -1, fmt.Errorf("no build matches %q", ref)
+	}
+	return index, nil
+}
+
+func matchInfo(info Info, ref string) bool {
+	return strings.EqualFold(info.Hash, ref) || info.Version.String() == ref
+}
+
+func matchAliases(aliases []Info, ref string) bool {
+	for _, alias := range aliases {
+		if matchInfo(alias, ref) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapiref/builds"
+)
+
+func init() {
+	AddCommand("bisect", CommandInfo{
+		Description: "List each build that changed an API element.",
+		Options: map[string]*flags.Option{
+			"field": &flags.Option{
+				Description: "Only list changes to the given field of the element, such as Tags or Security.",
+				ValueName:   "NAME",
+			},
+		},
+		Command: &BisectCommand{},
+	})
+}
+
+// BisectCommand scans the patches of the manifest for actions that touch the
+// element referred to by the first argument. The reference is parsed by
+// builds.ParseRef. For each matching action, the hash, version, and date of
+// the build are printed, followed by a description of the action.
+type BisectCommand struct {
+	Field string `short:"f" long:"field"`
+}
+
+func (cmd *BisectCommand) Run(data *Data, args []string) (err error) {
+	if len(args) == 0 {
+		return errors.New("expected reference to API element")
+	}
+	ref, err := builds.ParseRef(args[0], cmd.Field)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	var found bool
+	for _, p := range data.Manifest.Patches {
+		for i := range p.Actions {
+			action := &p.Actions[i]
+			if !ref.Match(action) {
+				continue
+			}
+			found = true
+			fmt.Fprintf(w, "%s\t%s\t%s\t",
+				p.Info.Hash,
+				p.Info.Version,
+				p.Info.Date.Format("2006-01-02 15:04:05"),
+			)
+			if action.Type == patch.Change {
+				fmt.Fprintf(w, "%s %s: %s → %s\n",
+					action.ElementName(),
+					action.Field,
+					action.Prev.String(),
+					action.Next.String(),
+				)
+			} else {
+				fmt.Fprintf(w, "%s %s %s\n",
+					builds.PatchTypeString(action.Type, "ed"),
+					action.GetElementType(),
+					action.ElementName(),
+				)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no changes to %s", ref)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
@@ -15,6 +16,7 @@ import (
 	"github.com/alecthomas/chroma"
 	chhtml "github.com/alecthomas/chroma/formatters/html"
 	"github.com/alecthomas/chroma/lexers"
+	"github.com/anaminus/but"
 	"github.com/gomarkdown/markdown/ast"
 	mdhtml "github.com/gomarkdown/markdown/html"
 	"github.com/robloxapi/rbxapiref/builds"
@@ -145,20 +147,15 @@ func (data *Data) GenerateHistoryElements(entity interface{}, button bool, ascen
 }
 
 func (data *Data) RenderPages(pages []Page) error {
-	var rootData struct {
-		Data     *Data
-		MainPage *Page
-		Page     *Page
-	}
-	rootData.Data = data
+	var main *Page
 	// Treat first page with unspecified filename as main page.
 	for _, page := range pages {
 		if page.File == "" {
-			rootData.MainPage = &page
+			main = &page
 			break
 		}
 	}
-	if rootData.MainPage == nil {
+	if main == nil {
 		return errors.New("no main template")
 	}
 	for _, page := range pages {
@@ -169,19 +166,182 @@ func (data *Data) RenderPages(pages []Page) error {
 		if err != nil {
 			return fmt.Errorf("create file: %w", err)
 		}
-		if page.Data == nil {
-			page.Data = data
-		}
-		rootData.Page = &page
-		err = data.Templates.ExecuteTemplate(file, rootData.MainPage.Template, rootData)
+		err = data.RenderPage(file, main, page)
 		file.Close()
 		if err != nil {
-			return fmt.Errorf("generate page: %w", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderPage renders a single page to w, using main as the main page.
+func (data *Data) RenderPage(w io.Writer, main *Page, page Page) error {
+	if page.Data == nil {
+		page.Data = data
+	}
+	rootData := struct {
+		Data     *Data
+		MainPage *Page
+		Page     *Page
+	}{data, main, &page}
+	if err := data.Templates.ExecuteTemplate(w, main.Template, rootData); err != nil {
+		return fmt.Errorf("generate page: %w", err)
+	}
+	return nil
+}
+
+// SaveManifest writes the manifest to its location within the output,
+// compressed according to the settings. The manifest is written to a temporary
+// file that then replaces the existing manifest, so that an interrupted save
+// never leaves a partial manifest.
+func (data *Data) SaveManifest() error {
+	var buf bytes.Buffer
+	data.Manifest.Compression = data.Settings.Output.Compression
+	if err := manifest.Encode(&buf, data.Manifest); err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	return replaceFile(data.Settings.Output.AbsFilePath("manifest"), "manifest", &buf)
+}
+
+// replaceFile writes buf to a temporary file that then replaces the file at
+// path. name describes the file in errors.
+func replaceFile(path, name string, buf *bytes.Buffer) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create %s directory: %w", name, err)
+	}
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
 		}
+	}()
+
+	if _, err = buf.WriteTo(f); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if err = f.Chmod(0644); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", name, err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", name, err)
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("replace %s: %w", name, err)
 	}
 	return nil
 }
 
+// GenerateEntities generates entities from the patches of the manifest. If the
+// output has an entity cache, the entities cached by a previous run are
+// updated with only the patches merged since then, and the result is cached
+// for the next run. The entities are generated from scratch if the cache is
+// missing, unreadable, or does not match the manifest. Returns an error only
+// if the cache could not be written.
+//
+// The entities are viewed from the security context of the output settings.
+func (data *Data) GenerateEntities() error {
+	patches := data.Manifest.Patches
+	if data.Settings.Output.EntityCache == "" {
+		data.Entities = entities.GenerateEntities(patches)
+		data.Entities.SecurityContext = data.Settings.Output.SecurityContext
+		return nil
+	}
+
+	path := data.Settings.Output.EntityCache
+	data.Entities = nil
+	if f, err := os.Open(path); err == nil {
+		ents, err := entities.ReadCache(bufio.NewReader(f))
+		f.Close()
+		if err == nil {
+			cached := ents.Applied()
+			if ents.Update(patches) {
+				but.Logf("ENTITIES cached %d patches, applied %d\n", cached, len(patches)-cached)
+				data.Entities = ents
+			}
+		}
+	}
+	if data.Entities == nil {
+		data.Entities = entities.GenerateEntities(patches)
+	}
+	data.Entities.SecurityContext = data.Settings.Output.SecurityContext
+
+	var buf bytes.Buffer
+	if err := data.Entities.WriteCache(&buf); err != nil {
+		return err
+	}
+	return replaceFile(path, "entity cache", &buf)
+}
+
+// GenerateReplacements adds the curated replacements of removed entities, if
+// a file of replacements is specified.
+func (data *Data) GenerateReplacements() error {
+	if data.Settings.Input.Replacements == "" {
+		return nil
+	}
+	f, err := os.Open(data.Settings.Input.Replacements)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	replacements, err := entities.ReadReplacements(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	return data.Entities.AddReplacements(replacements)
+}
+
+// GenerateLibraries adds the curated Lua libraries and globals, if a file of
+// libraries is specified.
+func (data *Data) GenerateLibraries() error {
+	if data.Settings.Input.Libraries == "" {
+		return nil
+	}
+	f, err := os.Open(data.Settings.Input.Libraries)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	libraries, err := entities.ReadLibraries(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	data.Entities.AddLibraries(libraries)
+	return nil
+}
+
+// GenerateChannels records the presence of each entity in each release
+// channel, adding entities for elements that exist only in additional
+// channels. Because the entities are regenerated, it must be called before
+// curated additions are added. Does nothing if the manifest has no additional
+// channels.
+func (data *Data) GenerateChannels() {
+	if len(data.Manifest.Channels) == 0 {
+		return
+	}
+	patches := data.Manifest.Patches
+	channels := []entities.ChannelAPI{{
+		Name: data.Settings.Build.Channel,
+		Root: builds.Snapshot(patches, len(patches)-1),
+	}}
+	for _, channel := range data.Manifest.Channels {
+		patches := channel.Patches
+		channels = append(channels, entities.ChannelAPI{
+			Name: channel.Name,
+			Root: builds.Snapshot(patches, len(patches)-1),
+		})
+	}
+	data.Entities.SetChannels(channels)
+}
+
 func (data *Data) GenerateMetadata() error {
 	if data.ResOnly {
 		return nil
@@ -393,6 +553,55 @@ func generateDocStatus(entity interface{}) (s entities.DocStatus) {
 		if s.ExamplesStatus < 2 {
 			s.ExamplesStatus = 0
 		}
+	case *entities.Library:
+		// Like enums, examples belong to the members of a library.
+		total += 2
+		if s.SummaryStatus >= 3 {
+			count++
+		}
+		if s.DetailsStatus >= 3 {
+			count++
+		}
+		if s.ExamplesStatus < 2 {
+			s.ExamplesStatus = 0
+		}
+		for _, member := range entity.MemberList {
+			total += 3
+			if member.DocStatus.SummaryStatus >= 3 {
+				count++
+			}
+			if member.DocStatus.DetailsStatus >= 3 {
+				count++
+			}
+			if member.DocStatus.ExamplesStatus >= 3 {
+				count++
+			}
+			if s.HasDocument {
+				if member.DocStatus.SummaryStatus == 0 {
+					member.DocStatus.SummaryStatus = 1
+				}
+				if member.DocStatus.DetailsStatus == 0 {
+					member.DocStatus.DetailsStatus = 1
+				}
+				if member.DocStatus.ExamplesStatus == 0 {
+					member.DocStatus.ExamplesStatus = 1
+				}
+				if member.DocStatus.AggregateStatus == 0 {
+					member.DocStatus.AggregateStatus = 1
+				}
+			}
+		}
+	case *entities.LibraryMember:
+		total += 3
+		if s.SummaryStatus >= 3 {
+			count++
+		}
+		if s.DetailsStatus >= 3 {
+			count++
+		}
+		if s.ExamplesStatus >= 3 {
+			count++
+		}
 	case entities.TypeCategory:
 		for _, typ := range entity.Types {
 			total += 3
@@ -520,11 +729,22 @@ func (data *Data) GenerateDocuments() {
 				GenerateDocumentTypeIDs(entity.Document)
 			}
 		}
+		for _, entity := range data.Entities.LibraryList {
+			if entity.Document, _ = apiDir.Query("library", entity.ID).(entities.Document); entity.Document != nil {
+				entity.Document.SetRender(renderer())
+				for _, member := range entity.MemberList {
+					if member.Document, _ = entity.Document.Query("Members", member.ID[1]).(entities.Document); member.Document != nil {
+						member.Document.SetRender(renderer())
+					}
+				}
+			}
+		}
 	}
 
 	total := float64(len(data.Entities.ClassList) +
 		len(data.Entities.EnumList) +
-		len(data.Entities.TypeList))
+		len(data.Entities.TypeList) +
+		len(data.Entities.LibraryList))
 	var count float64
 	for _, entity := range data.Entities.ClassList {
 		for _, member := range entity.MemberList {
@@ -544,5 +764,12 @@ func (data *Data) GenerateDocuments() {
 		entity.DocStatus = generateDocStatus(entity)
 		count += entity.DocStatus.AggregateProgress
 	}
+	for _, entity := range data.Entities.LibraryList {
+		for _, member := range entity.MemberList {
+			member.DocStatus = generateDocStatus(member)
+		}
+		entity.DocStatus = generateDocStatus(entity)
+		count += entity.DocStatus.AggregateProgress
+	}
 	data.Entities.Coverage = float32(count / total)
 }
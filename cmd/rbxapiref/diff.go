@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapiref/builds"
+	"github.com/robloxapi/rbxapiref/entities"
+)
+
+func init() {
+	AddCommand("diff", CommandInfo{
+		Description: "Compare the API of two builds.",
+		Options: map[string]*flags.Option{
+			"format": &flags.Option{
+				Description: "The format of the report.",
+				ValueName:   "FORMAT",
+			},
+			"output": &flags.Option{
+				Description: "Write to a file instead of standard output.",
+				ValueName:   "PATH",
+			},
+		},
+		Command: &DiffCommand{},
+	})
+}
+
+// DiffCommand compares the API of two builds, referred to by the first and
+// second arguments. Each reference is resolved by builds.FindPatch. The
+// resulting actions are grouped by class and enum.
+type DiffCommand struct {
+	Format string `short:"f" long:"format" choice:"html" choice:"md" choice:"json" default:"md"`
+	Output string `short:"o" long:"output"`
+}
+
+func (cmd *DiffCommand) Run(data *Data, args []string) (err error) {
+	if len(args) < 2 {
+		return errors.New("expected two build references")
+	}
+	patches := data.Manifest.Patches
+	from, err := builds.FindPatch(patches, args[0])
+	if err != nil {
+		return err
+	}
+	to, err := builds.FindPatch(patches, args[1])
+	if err != nil {
+		return err
+	}
+	diff := builds.DiffPatches(patches, from, to)
+
+	var w io.Writer = os.Stdout
+	if cmd.Output != "" {
+		f, err := os.Create(cmd.Output)
+		if err != nil {
+			return fmt.Errorf("create report: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	switch cmd.Format {
+	case "html":
+		err = cmd.writeHTML(bw, data, diff)
+	case "json":
+		je := json.NewEncoder(bw)
+		je.SetEscapeHTML(false)
+		je.SetIndent("", "\t")
+		err = je.Encode(diff)
+	default:
+		err = writeDiffMarkdown(bw, diff)
+	}
+	if err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return bw.Flush()
+}
+
+// writeHTML renders the diff as a page of the site.
+func (cmd *DiffCommand) writeHTML(w io.Writer, data *Data, diff builds.Diff) (err error) {
+	data.Entities = entities.GenerateEntities(data.Manifest.Patches)
+	data.Templates, err = CompileTemplates(data.Settings.Input.Templates, TemplateFuncs(data))
+	if err != nil {
+		return fmt.Errorf("open template: %w", err)
+	}
+	// Only the layout of the main page is needed, so avoid fetching resources
+	// that are generated by the site.
+	data.ResOnly = true
+	main := generatePageMain(data)[0]
+	page := newDiffPage(data.Settings.Output, diff,
+		[]Resource{{Name: "updates.css", Attr: []Attr{{"id", "updates-style"}}}},
+		[]Resource{{Name: "updates.js", Attr: []Attr{{"async", ""}}}},
+	)
+	return data.RenderPage(w, &main, page)
+}
+
+func writeDiffMarkdown(w io.Writer, diff builds.Diff) error {
+	ew := &errWriter{w: w}
+	ew.printf("# API differences from v%s to v%s\n\n", diff.From.Version, diff.To.Version)
+	ew.printf("- From: `%s` (v%s, %s)\n", diff.From.Hash, diff.From.Version, diff.From.Date.Format("2006-01-02 15:04"))
+	ew.printf("- To: `%s` (v%s, %s)\n", diff.To.Hash, diff.To.Version, diff.To.Date.Format("2006-01-02 15:04"))
+	if len(diff.Groups) == 0 {
+		ew.printf("\nNo changes.\n")
+	}
+	for _, group := range diff.Groups {
+		ew.printf("\n## %s %s\n\n", group.Type, group.Name)
+		for _, action := range group.Actions {
+			ew.printf("- %s\n", action.String())
+			if action.Type == patch.Change || action.GetMember() != nil || action.EnumItem != nil {
+				continue
+			}
+			for _, sub := range builds.MakeSubactions(action) {
+				ew.printf("  - %s\n", sub.String())
+			}
+		}
+		for _, action := range group.Metadata {
+			ew.printf("- %s\n", action.String())
+		}
+	}
+	return ew.err
+}
+
+// errWriter writes formatted text, retaining the first error that occurs.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
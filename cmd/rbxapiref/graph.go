@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/robloxapi/rbxapiref/entities"
+	"github.com/robloxapi/rbxapiref/graph"
+)
+
+func init() {
+	AddCommand("graph", CommandInfo{
+		Description: "Export the class hierarchy and type references as a graph.",
+		Options: map[string]*flags.Option{
+			"format": &flags.Option{
+				Description: "The format of the graph.",
+				ValueName:   "FORMAT",
+			},
+			"output": &flags.Option{
+				Description: "Write to a file instead of standard output.",
+				ValueName:   "PATH",
+			},
+			"depth": &flags.Option{
+				Description: "The number of levels of subclasses to include below each root. Zero includes every level.",
+				ValueName:   "N",
+			},
+			"removed": &flags.Option{
+				Description: "Include removed classes and referred entities.",
+			},
+			"refs": &flags.Option{
+				Description: "Include references of a kind (" + strings.Join(graph.RefKinds, ", ") + "), or none. May be repeated. All kinds are included by default.",
+				ValueName:   "KIND",
+			},
+		},
+		Command: &GraphCommand{},
+	})
+}
+
+// GraphCommand exports the class hierarchy, along with the classes, enums, and
+// types referred to by the members of each class. Each argument is the name
+// of a class from which the hierarchy is traversed. For example:
+//
+//	rbxapiref graph --format graphml --depth 2 --refs enum BasePart
+type GraphCommand struct {
+	Format  string   `short:"f" long:"format" choice:"dot" choice:"graphml" choice:"json" default:"dot"`
+	Output  string   `short:"o" long:"output"`
+	Depth   int      `long:"depth"`
+	Removed bool     `long:"removed"`
+	Refs    []string `long:"refs"`
+}
+
+func (cmd *GraphCommand) Run(data *Data, args []string) (err error) {
+	opts := graph.Options{
+		Roots:   args,
+		Depth:   cmd.Depth,
+		Removed: cmd.Removed,
+	}
+	if cmd.Refs != nil {
+		opts.RefKinds = []string{}
+		for _, kind := range cmd.Refs {
+			if kind != "none" {
+				opts.RefKinds = append(opts.RefKinds, kind)
+			}
+		}
+	}
+	data.Entities = entities.GenerateEntities(data.Manifest.Patches)
+	g, err := graph.Build(data.Entities, opts)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	switch cmd.Format {
+	case "graphml":
+		err = g.WriteGraphML(&buf)
+	case "json":
+		err = g.WriteJSON(&buf)
+	default:
+		err = g.WriteDOT(&buf)
+	}
+	if err != nil {
+		return fmt.Errorf("write graph: %w", err)
+	}
+	if cmd.Output != "" {
+		return replaceFile(cmd.Output, "graph", &buf)
+	}
+	_, err = buf.WriteTo(os.Stdout)
+	return err
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/anaminus/but"
 	"github.com/jessevdk/go-flags"
+	"github.com/robloxapi/rbxapiref/builds"
 	"github.com/robloxapi/rbxapiref/entities"
 	"github.com/robloxapi/rbxapiref/manifest"
 	"github.com/robloxapi/rbxapiref/settings"
@@ -259,12 +262,15 @@ func (r Range) Norm(length int) Range {
 type FlagOptions struct {
 	Settings string `short:"s" long:"settings"`
 	Force    bool   `long:"force"`
+	Recover  bool   `long:"recover"`
 	ResOnly  bool   `long:"res-only"`
 	Range    Range  `long:"range"`
 	UseGit   bool   `long:"use-git"`
 	NoGit    bool   `long:"no-git"`
 	Rewind   bool   `long:"rewind"`
 	NoRewind bool   `long:"no-rewind"`
+	Preview  bool   `long:"preview"`
+	Security string `long:"security"`
 }
 
 var options = map[string]*flags.Option{
@@ -275,6 +281,9 @@ var options = map[string]*flags.Option{
 	"force": &flags.Option{
 		Description: "Force a complete rebuild.",
 	},
+	"recover": &flags.Option{
+		Description: "Keep the builds of a corrupt manifest that precede the corruption.",
+	},
 	"res-only": &flags.Option{
 		Description: "Only regenerate resource files.",
 	},
@@ -294,27 +303,94 @@ var options = map[string]*flags.Option{
 	"no-rewind": &flags.Option{
 		Description: "Force no rewinding.",
 	},
+	"preview": &flags.Option{
+		Description: "Force builds that are not yet live to be included as unreleased.",
+	},
+	"security": &flags.Option{
+		Description: "View the API from a security context, hiding members that require a more privileged context.",
+		ValueName:   "CONTEXT",
+	},
 }
 
-func ParseOptions(data interface{}, opts flags.Options) *flags.Parser {
-	fp := flags.NewParser(data, opts)
+// Command is implemented by a subcommand of the program.
+type Command interface {
+	// Run runs the command. The data contains the loaded settings and
+	// manifest, and args contains the remaining positional arguments.
+	Run(data *Data, args []string) error
+}
+
+// CommandInfo describes a subcommand of the program.
+type CommandInfo struct {
+	// Description is a short description of the command.
+	Description string
+	// Options describes the options of the command, mapped by long name.
+	Options map[string]*flags.Option
+	// Command is the command to run. Options are parsed into the value.
+	Command Command
+}
+
+var commands = map[string]CommandInfo{}
+
+// AddCommand registers a subcommand of the program with the given name.
+func AddCommand(name string, info CommandInfo) {
+	if _, ok := commands[name]; ok {
+		panic("command " + name + " already registered")
+	}
+	commands[name] = info
+}
+
+func setOptionInfo(group *flags.Group, options map[string]*flags.Option) {
 	for name, info := range options {
-		opt := fp.FindOptionByLongName(name)
+		opt := group.FindOptionByLongName(name)
 		if opt == nil {
 			continue
 		}
 		opt.Description = info.Description
 		opt.ValueName = info.ValueName
 	}
+}
+
+func ParseOptions(data interface{}, opts flags.Options) *flags.Parser {
+	fp := flags.NewParser(data, opts)
+	setOptionInfo(fp.Group, options)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		info := commands[name]
+		cmd, err := fp.AddCommand(name, info.Description, "", info.Command)
+		if err != nil {
+			panic(err)
+		}
+		setOptionInfo(cmd.Group, info.Options)
+	}
+	fp.SubcommandsOptional = true
 	return fp
 }
 
+// toolVersion returns a string identifying the program and the version of its
+// module, if available.
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return "rbxapiref " + info.Main.Version
+	}
+	return "rbxapiref"
+}
+
+// checkpointInterval is the minimum duration between saves of the manifest
+// while merging builds.
+const checkpointInterval = time.Minute
+
 func main() {
 	var err error
+	manifest.Tool = toolVersion()
 
 	// Parse flags.
 	var opt FlagOptions
 	var filters []string
+	var command *flags.Command
 	{
 		fp := ParseOptions(&opt, flags.Default|flags.PassAfterNonOption)
 		var err error
@@ -323,6 +399,7 @@ func main() {
 			return
 		}
 		but.IfFatal(err, "flag parser error")
+		command = fp.Active
 	}
 
 	// Initialize root.
@@ -340,10 +417,18 @@ func main() {
 	} else if opt.UseGit {
 		data.Settings.Input.UseGit = true
 	}
-	if opt.NoRewind {
-		data.Settings.Build.DisableRewind = true
+	if opt.Preview {
+		data.Settings.Build.Rewind = builds.RewindPreview
+	} else if opt.NoRewind {
+		data.Settings.Build.Rewind = builds.RewindDisable
 	} else if opt.Rewind {
-		data.Settings.Build.DisableRewind = false
+		data.Settings.Build.Rewind = builds.RewindCut
+	}
+	if opt.Security != "" {
+		data.Settings.Output.SecurityContext = opt.Security
+	}
+	if context := data.Settings.Output.SecurityContext; context != "" && !entities.IsSecurityContext(context) {
+		but.IfFatal(fmt.Errorf("unknown security context %q", context))
 	}
 
 	// Load manifest.
@@ -351,11 +436,63 @@ func main() {
 	if !opt.Force {
 		if b, err := ioutil.ReadFile(manifestPath); err == nil {
 			data.Manifest, err = manifest.Decode(bytes.NewReader(b))
+			var derr *manifest.DecodeError
+			if opt.Recover && errors.As(err, &derr) {
+				but.Log("RECOVER", len(data.Manifest.Patches), "patches:", err)
+				err = nil
+			}
 			but.IfFatal(err, "read manifest")
 		}
 	}
 
+	if command != nil {
+		// Run subcommand instead of generating the site.
+		but.IfFatal(commands[command.Name].Command.Run(data, filters), command.Name)
+		return
+	}
+
 	if !opt.ResOnly {
+		// Drop the cached patches affected by settings that changed since
+		// the last merge. Remaining patches are also checked individually
+		// against their configs while merging.
+		record := data.Settings.Build.Record()
+		if prev := data.Manifest.Settings; prev != nil {
+			for _, field := range prev.Changes(record) {
+				but.Log("SETTINGS CHANGED", field)
+			}
+			data.Manifest.Patches = data.Settings.Build.Invalidate(*prev, "", data.Manifest.Patches)
+			for i, channel := range data.Manifest.Channels {
+				data.Manifest.Channels[i].Patches = data.Settings.Build.Invalidate(*prev, channel.Name, channel.Patches)
+			}
+		}
+		// Checkpoints contain only patches that are valid under the current
+		// settings.
+		data.Manifest.Settings = &record
+
+		// Save the manifest periodically while merging, so that an
+		// interrupted run resumes from where it stopped. The checkpoint
+		// returned for a channel replaces the patches of that channel, or
+		// of the main channel if the name is empty.
+		lastSave := time.Now()
+		checkpoint := func(channel string) func([]builds.Patch) {
+			return func(patches []builds.Patch) {
+				if time.Since(lastSave) < checkpointInterval {
+					return
+				}
+				if channel == "" {
+					data.Manifest.Patches = patches
+				} else if cached := data.Manifest.Channel(channel); cached != nil {
+					cached.Patches = patches
+				} else {
+					data.Manifest.Channels = append(data.Manifest.Channels, manifest.Channel{Name: channel, Patches: patches})
+				}
+				if !but.IfError(data.SaveManifest(), "checkpoint") {
+					but.Log("CHECKPOINT", len(patches), "patches")
+				}
+				lastSave = time.Now()
+			}
+		}
+
 		// Fetch builds.
 		builds, err := data.Settings.Build.Fetch()
 		but.IfFatal(err)
@@ -366,12 +503,44 @@ func main() {
 		}
 
 		// Merge uncached builds.
-		data.Manifest.Patches, err = data.Settings.Build.Merge(data.Manifest.Patches, builds)
+		data.Manifest.Patches, err = data.Settings.Build.Merge(data.Manifest.Patches, builds, checkpoint(""))
 		but.IfFatal(err)
+
+		// Merge uncached builds of each additional release channel.
+		channels := make([]manifest.Channel, 0, len(data.Settings.Build.Channels))
+		for _, name := range data.Settings.Build.ChannelNames() {
+			but.Log("CHANNEL", name)
+			list, err := data.Settings.Build.FetchChannel(name)
+			but.IfFatal(err)
+			channel := manifest.Channel{Name: name}
+			if cached := data.Manifest.Channel(name); cached != nil {
+				channel.Patches = cached.Patches
+			}
+			channel.Patches, err = data.Settings.Build.Merge(channel.Patches, list, checkpoint(name))
+			but.IfFatal(err)
+			channels = append(channels, channel)
+		}
+		data.Manifest.Channels = channels
+
+		if data.Settings.Build.Metadata {
+			// Record changes to reflection metadata.
+			data.Settings.Build.MergeMetadata(data.Manifest.Patches, checkpoint(""))
+			for _, channel := range data.Manifest.Channels {
+				but.Log("CHANNEL", channel.Name)
+				data.Settings.Build.MergeMetadata(channel.Patches, checkpoint(channel.Name))
+			}
+		}
+
+		// Save the merged manifest before rendering, so that a failure to
+		// render does not discard fetched patches.
+		but.IfFatal(data.SaveManifest())
 	}
 
 	// Generate entities.
-	data.Entities = entities.GenerateEntities(data.Manifest.Patches)
+	but.IfError(data.GenerateEntities(), "cache entities")
+	data.GenerateChannels()
+	but.IfError(data.GenerateReplacements(), "add replacements")
+	but.IfError(data.GenerateLibraries(), "add libraries")
 	but.IfFatal(data.GenerateMetadata())
 	data.GenerateDocuments()
 
@@ -407,21 +576,4 @@ func main() {
 		but.IfFatal(w.Flush(), "write search database")
 		f.Close()
 	}
-
-	// Save manifest.
-	{
-		var buf bytes.Buffer
-		err := manifest.Encode(&buf, data.Manifest)
-		but.IfFatal(err, "encode manifest")
-
-		f, err := os.Create(manifestPath)
-		but.IfFatal(err, "create manifest")
-		defer f.Close()
-
-		_, err = buf.WriteTo(f)
-		but.IfFatal(err, "write manifest")
-
-		err = f.Sync()
-		but.IfFatal(err, "sync manifest")
-	}
 }
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/anaminus/but"
+	"github.com/jessevdk/go-flags"
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapiref/builds"
+	"github.com/robloxapi/rbxapiref/manifest"
+)
+
+func init() {
+	AddCommand("manifest", CommandInfo{
+		Description: "Inspect or edit the manifest.",
+		Options: map[string]*flags.Option{
+			"format": &flags.Option{
+				Description: "The format of the output. Defaults to text, or json when converting.",
+				ValueName:   "FORMAT",
+			},
+			"output": &flags.Option{
+				Description: "Write to a file instead of standard output.",
+				ValueName:   "PATH",
+			},
+			"channel": &flags.Option{
+				Description: "Select the patches of a release channel instead of the main channel.",
+				ValueName:   "NAME",
+			},
+		},
+		Command: &ManifestCommand{},
+	})
+}
+
+// ManifestCommand inspects or edits the manifest. The first argument selects
+// the action to perform:
+//
+//	list               List each patch.
+//	show REF           Print the actions of a patch.
+//	convert [PATH]     Convert the manifest, or the manifest file at PATH.
+//	remove REF...      Remove patches.
+//	replace REF PATH   Replace a patch with the JSON-encoded patch at PATH.
+//
+// Each REF refers to a patch, as resolved by builds.FindPatch. The JSON
+// produced by "show -f json" is accepted by "replace", so that a patch can be
+// edited by hand.
+//
+// Actions that edit the manifest write it back to its location. A following
+// patch that was based on a removed or replaced build no longer matches the
+// build preceding it, and is recomputed by the next run.
+type ManifestCommand struct {
+	Format  string `short:"f" long:"format" choice:"text" choice:"json" choice:"binary"`
+	Output  string `short:"o" long:"output"`
+	Channel string `short:"c" long:"channel"`
+}
+
+func (cmd *ManifestCommand) Run(data *Data, args []string) (err error) {
+	if len(args) < 1 {
+		return errors.New("expected action")
+	}
+	action, args := args[0], args[1:]
+	switch action {
+	case "list":
+		return cmd.write(func(w io.Writer) error { return cmd.list(w, data) })
+	case "show":
+		if len(args) < 1 {
+			return errors.New("expected patch reference")
+		}
+		return cmd.write(func(w io.Writer) error { return cmd.show(w, data, args[0]) })
+	case "convert":
+		return cmd.write(func(w io.Writer) error { return cmd.convert(w, data, args) })
+	case "remove":
+		if len(args) < 1 {
+			return errors.New("expected patch reference")
+		}
+		return cmd.remove(data, args)
+	case "replace":
+		if len(args) < 2 {
+			return errors.New("expected patch reference and file")
+		}
+		return cmd.replace(data, args[0], args[1])
+	}
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// patches returns the list of patches of the selected channel.
+func (cmd *ManifestCommand) patches(man *manifest.Manifest) (*[]builds.Patch, error) {
+	if cmd.Channel == "" {
+		return &man.Patches, nil
+	}
+	channel := man.Channel(cmd.Channel)
+	if channel == nil {
+		return nil, fmt.Errorf("unknown channel %q", cmd.Channel)
+	}
+	return &channel.Patches, nil
+}
+
+// write calls f with the selected output. Output to a file is buffered and
+// written with replaceFile, so that the file is left untouched if f fails.
+func (cmd *ManifestCommand) write(f func(w io.Writer) error) error {
+	if cmd.Output != "" {
+		var buf bytes.Buffer
+		if err := f(&buf); err != nil {
+			return err
+		}
+		return replaceFile(cmd.Output, "output", &buf)
+	}
+	bw := bufio.NewWriter(os.Stdout)
+	if err := f(bw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (cmd *ManifestCommand) list(w io.Writer, data *Data) error {
+	if cmd.Format != "" && cmd.Format != "text" {
+		return fmt.Errorf("cannot list in %s format", cmd.Format)
+	}
+	patches, err := cmd.patches(data.Manifest)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	ew := &errWriter{w: tw}
+	ew.printf("INDEX\tHASH\tVERSION\tDATE\tADD\tCHANGE\tREMOVE\tNOTES\n")
+	for i, p := range *patches {
+		var counts [3]int
+		for _, action := range p.Actions {
+			switch action.Type {
+			case patch.Add:
+				counts[0]++
+			case patch.Change:
+				counts[1]++
+			case patch.Remove:
+				counts[2]++
+			}
+		}
+		var notes string
+		if p.Unreleased {
+			notes += "unreleased "
+		}
+		if len(p.Aliases) > 0 {
+			notes += fmt.Sprintf("aliases:%d ", len(p.Aliases))
+		}
+		ew.printf("%d\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			i,
+			p.Info.Hash,
+			p.Info.Version,
+			p.Info.Date.Format("2006-01-02 15:04:05"),
+			counts[0], counts[1], counts[2],
+			notes,
+		)
+	}
+	if ew.err != nil {
+		return ew.err
+	}
+	return tw.Flush()
+}
+
+func (cmd *ManifestCommand) show(w io.Writer, data *Data, ref string) error {
+	patches, err := cmd.patches(data.Manifest)
+	if err != nil {
+		return err
+	}
+	i, err := builds.FindPatch(*patches, ref)
+	if err != nil {
+		return err
+	}
+	p := (*patches)[i]
+	switch cmd.Format {
+	case "", "text":
+		ew := &errWriter{w: w}
+		ew.printf("Build: %s\n", p.Info)
+		if p.Prev != nil {
+			ew.printf("Previous: %s\n", *p.Prev)
+		}
+		for _, alias := range p.Aliases {
+			ew.printf("Alias: %s\n", alias)
+		}
+		ew.printf("Config: %s\n", p.Config)
+		if p.Source != nil {
+			if p.Source.Location != "" {
+				ew.printf("Location: %s\n", p.Source.Location)
+			}
+			if !p.Source.Time.IsZero() {
+				ew.printf("Retrieved: %s\n", p.Source.Time)
+			}
+			ew.printf("Fingerprint: %s\n", p.Source.Fingerprint)
+		}
+		if p.Unreleased {
+			ew.printf("Unreleased: true\n")
+		}
+		ew.printf("Actions: %d\n", len(p.Actions))
+		for j, action := range p.Actions {
+			ew.printf("%d\t%s\n", j, action.String())
+		}
+		if p.HasMetadata {
+			ew.printf("Metadata: %d\n", len(p.Metadata))
+			for j, action := range p.Metadata {
+				ew.printf("%d\t%s\n", j, action.String())
+			}
+		}
+		if len(p.Extension) > 0 {
+			ew.printf("Extension: %d\n", len(p.Extension))
+			for _, key := range p.Extension.Keys() {
+				fields := p.Extension[key]
+				names := fields.AllNames()
+				for _, name := range names {
+					ew.printf("\t%s %s = %s\n", key, name, builds.WrapValue(fields[name]).String())
+				}
+			}
+		}
+		return ew.err
+	case "json":
+		je := json.NewEncoder(w)
+		je.SetEscapeHTML(false)
+		je.SetIndent("", "\t")
+		return je.Encode(p)
+	}
+	return fmt.Errorf("cannot show in %s format", cmd.Format)
+}
+
+// readManifestFile decodes the manifest file at path, in either the binary or
+// JSON format.
+func readManifestFile(path string) (*manifest.Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		return manifest.DecodeJSON(bytes.NewReader(b))
+	}
+	return manifest.Decode(bytes.NewReader(b))
+}
+
+func (cmd *ManifestCommand) convert(w io.Writer, data *Data, args []string) (err error) {
+	man := data.Manifest
+	if len(args) > 0 {
+		if man, err = readManifestFile(args[0]); err != nil {
+			return fmt.Errorf("read manifest: %w", err)
+		}
+	}
+	switch cmd.Format {
+	case "", "json":
+		return manifest.EncodeJSON(w, man)
+	case "binary":
+		// Set the compression on a copy, so that the loaded manifest is not
+		// modified.
+		c := *man
+		c.Compression = data.Settings.Output.Compression
+		return manifest.Encode(w, &c)
+	}
+	return fmt.Errorf("cannot convert to %s format", cmd.Format)
+}
+
+func (cmd *ManifestCommand) remove(data *Data, refs []string) error {
+	patches, err := cmd.patches(data.Manifest)
+	if err != nil {
+		return err
+	}
+	// Resolve each reference before removing anything, so that indices remain
+	// valid.
+	remove := map[int]bool{}
+	for _, ref := range refs {
+		i, err := builds.FindPatch(*patches, ref)
+		if err != nil {
+			return err
+		}
+		remove[i] = true
+	}
+	kept := (*patches)[:0]
+	for i, p := range *patches {
+		if remove[i] {
+			but.Log("REMOVE", p.Info)
+			continue
+		}
+		kept = append(kept, p)
+	}
+	*patches = kept
+	return data.SaveManifest()
+}
+
+func (cmd *ManifestCommand) replace(data *Data, ref, path string) error {
+	patches, err := cmd.patches(data.Manifest)
+	if err != nil {
+		return err
+	}
+	i, err := builds.FindPatch(*patches, ref)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read patch: %w", err)
+	}
+	var p builds.Patch
+	if err := json.Unmarshal(b, &p); err != nil {
+		return fmt.Errorf("decode patch: %w", err)
+	}
+	for j := range p.Actions {
+		p.Actions[j].Index = j
+	}
+	for j := range p.Metadata {
+		p.Metadata[j].Index = j
+	}
+	but.Log("REPLACE", (*patches)[i].Info, "with", p.Info)
+	(*patches)[i] = p
+	return data.SaveManifest()
+}
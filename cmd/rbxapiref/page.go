@@ -15,6 +15,7 @@ import (
 	"github.com/robloxapi/rbxapiref/documents"
 	"github.com/robloxapi/rbxapiref/entities"
 	"github.com/robloxapi/rbxapiref/fetch"
+	"github.com/robloxapi/rbxapiref/manifest"
 	"github.com/robloxapi/rbxapiref/settings"
 )
 
@@ -211,6 +212,32 @@ func generatePageDocmon(output settings.Output, entities *entities.Entities) (pa
 	}}
 }
 
+func generatePageSecurity(output settings.Output, entities *entities.Entities) (pages []Page) {
+	return []Page{{
+		File: output.FilePath("security"),
+		Meta: Meta{
+			"Title":       Title("Security contexts"),
+			"Description": "Parts of the Roblox API that can be used from each security context.",
+		},
+		Styles:   []Resource{{Name: "security.css", Embed: true}},
+		Template: "security",
+		Data:     entities,
+	}}
+}
+
+func generatePageAnomalies(output settings.Output, entities *entities.Entities) (pages []Page) {
+	return []Page{{
+		File: output.FilePath("anomalies"),
+		Meta: Meta{
+			"Title":       Title("Anomalies"),
+			"Description": "Name collisions, redeclared members, and duplicate enum values in the Roblox API.",
+		},
+		Styles:   []Resource{{Name: "anomalies.css", Embed: true}},
+		Template: "anomalies",
+		Data:     entities,
+	}}
+}
+
 func generatePageUpdates(output settings.Output, patches []builds.Patch) (pages []Page) {
 	if len(patches) <= 1 {
 		return nil
@@ -398,14 +425,97 @@ func generatePageType(output settings.Output, types []*entities.Type) (pages []P
 	return pages
 }
 
+func generatePageLibrary(output settings.Output, libraries []*entities.Library) (pages []Page) {
+	styles := []Resource{{Name: "class.css"}}
+	pages = make([]Page, len(libraries))
+	for i, library := range libraries {
+		pages[i] = Page{
+			File: output.FilePath("library", library.ID),
+			Meta: Meta{
+				"Title":       Title(library.ID),
+				"Description": "Information about the " + library.ID + " library in the Roblox Lua API."},
+			Styles:       styles,
+			DocResources: NormalizeDocReferences(output, library.Document),
+			Template:     "library",
+			Data:         library,
+		}
+	}
+	return pages
+}
+
+func generatePageDiff(output settings.Output, patches []builds.Patch) (pages []Page) {
+	styles := []Resource{{Name: "updates.css", Attr: []Attr{{"id", "updates-style"}}}}
+	scripts := []Resource{{Name: "updates.js", Attr: []Attr{{"async", ""}}}}
+	for _, d := range output.Diffs {
+		from, err := builds.FindPatch(patches, d.From)
+		if err != nil {
+			but.Logf("SKIP DIFF %s...%s: %s\n", d.From, d.To, err)
+			continue
+		}
+		to, err := builds.FindPatch(patches, d.To)
+		if err != nil {
+			but.Logf("SKIP DIFF %s...%s: %s\n", d.From, d.To, err)
+			continue
+		}
+		pages = append(pages, newDiffPage(output, builds.DiffPatches(patches, from, to), styles, scripts))
+	}
+	return pages
+}
+
+func generatePageChannel(output settings.Output, channel string, man *manifest.Manifest) (pages []Page) {
+	if len(man.Patches) == 0 {
+		return nil
+	}
+	styles := []Resource{{Name: "updates.css", Attr: []Attr{{"id", "updates-style"}}}}
+	scripts := []Resource{{Name: "updates.js", Attr: []Attr{{"async", ""}}}}
+	for _, ch := range man.Channels {
+		if len(ch.Patches) == 0 {
+			continue
+		}
+		diff := builds.DiffChannels(channel, man.Patches, ch.Name, ch.Patches)
+		pages = append(pages, Page{
+			File: output.FilePath("channel", ch.Name),
+			Meta: Meta{
+				"Title":       Title(ch.Name + " Channel"),
+				"Description": "Differences in the Roblox Lua API between the " + channel + " and " + ch.Name + " channels.",
+			},
+			Styles:   styles,
+			Scripts:  scripts,
+			Template: "diff",
+			Data:     diff,
+		})
+	}
+	return pages
+}
+
+func newDiffPage(output settings.Output, diff builds.Diff, styles, scripts []Resource) Page {
+	title := "v" + diff.From.Version.String() + " to v" + diff.To.Version.String()
+	return Page{
+		File: output.FilePath("diff", diff.From.Hash, diff.To.Hash),
+		Meta: Meta{
+			"Title":       Title("Differences from " + title),
+			"Description": "Differences in the Roblox Lua API from " + title + ".",
+		},
+		Styles:   styles,
+		Scripts:  scripts,
+		Template: "diff",
+		Data:     diff,
+	}
+}
+
 func GeneratePages(data *Data) (pages []Page) {
 	pages = append(pages, generatePageMain(data)...)
 	pages = append(pages, generatePageIndex(data.Settings.Output)...)
 	pages = append(pages, generatePageAbout(data.Settings.Output)...)
 	pages = append(pages, generatePageDocmon(data.Settings.Output, data.Entities)...)
+	pages = append(pages, generatePageSecurity(data.Settings.Output, data.Entities)...)
+	pages = append(pages, generatePageAnomalies(data.Settings.Output, data.Entities)...)
 	pages = append(pages, generatePageUpdates(data.Settings.Output, data.Manifest.Patches)...)
+	pages = append(pages, generatePageDiff(data.Settings.Output, data.Manifest.Patches)...)
+	pages = append(pages, generatePageChannel(data.Settings.Output, data.Settings.Build.Channel, data.Manifest)...)
 	pages = append(pages, generatePageClass(data.Settings.Output, data.Entities.ClassList)...)
 	pages = append(pages, generatePageEnum(data.Settings.Output, data.Entities.EnumList)...)
 	pages = append(pages, generatePageType(data.Settings.Output, data.Entities.TypeList)...)
+	pages = append(pages, generatePageLibrary(data.Settings.Output, data.Entities.LibraryList)...)
 	return pages
 }
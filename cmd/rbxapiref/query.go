@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/robloxapi/rbxapiref/entities"
+	"github.com/robloxapi/rbxapiref/query"
+)
+
+func init() {
+	AddCommand("query", CommandInfo{
+		Description: "List the API elements selected by a query.",
+		Options: map[string]*flags.Option{
+			"format": &flags.Option{
+				Description: "The format of the list.",
+				ValueName:   "FORMAT",
+			},
+			"output": &flags.Option{
+				Description: "Write to a file instead of standard output.",
+				ValueName:   "PATH",
+			},
+		},
+		Command: &QueryCommand{},
+	})
+}
+
+// QueryCommand lists the entities selected by a query, which is formed by
+// joining each argument with a space. The syntax of a query is described by
+// query.Parse. For example:
+//
+//	rbxapiref query member where security=PluginSecurity and not removed
+type QueryCommand struct {
+	Format string `short:"f" long:"format" choice:"text" choice:"json" default:"text"`
+	Output string `short:"o" long:"output"`
+}
+
+// queryResult is an entity selected by a query, in the JSON format.
+type queryResult struct {
+	Type    string
+	Name    string
+	Removed bool `json:",omitempty"`
+}
+
+func (cmd *QueryCommand) Run(data *Data, args []string) (err error) {
+	if len(args) == 0 {
+		return errors.New("expected query")
+	}
+	q, err := query.Parse(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	data.Entities = entities.GenerateEntities(data.Manifest.Patches)
+	data.GenerateChannels()
+	data.GenerateDocuments()
+	results := q.Run(data.Entities)
+
+	var w io.Writer = os.Stdout
+	if cmd.Output != "" {
+		f, err := os.Create(cmd.Output)
+		if err != nil {
+			return fmt.Errorf("create list: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	switch cmd.Format {
+	case "json":
+		list := make([]queryResult, len(results))
+		for i, entity := range results {
+			list[i].Type, list[i].Name = query.Describe(entity)
+			list[i].Removed = entity.(entities.Entity).IsRemoved()
+		}
+		je := json.NewEncoder(bw)
+		je.SetEscapeHTML(false)
+		je.SetIndent("", "\t")
+		err = je.Encode(list)
+	default:
+		ew := &errWriter{w: bw}
+		for _, entity := range results {
+			typ, name := query.Describe(entity)
+			if entity.(entities.Entity).IsRemoved() {
+				ew.printf("%s\t%s\tremoved\n", typ, name)
+			} else {
+				ew.printf("%s\t%s\n", typ, name)
+			}
+		}
+		err = ew.err
+	}
+	if err != nil {
+		return fmt.Errorf("write list: %w", err)
+	}
+	return bw.Flush()
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/robloxapi/rbxapi"
@@ -14,7 +15,7 @@ import (
 
 main struct {
 	// Database version.
-	Version   uint:8 = 1
+	Version   uint:8 = searchDBVersion
 	// Number of icons.
 	IconCount uint:16
 	// Starting index of items that are classes. Subtracted from item index to
@@ -22,17 +23,30 @@ main struct {
 	ClassOffset uint:16
 	// Total number of items.
 	ItemCount uint:16
+	// Number of release channels. Zero if there are no additional channels.
+	ChannelCount uint:8
 	// List of ExplorerImageIndex for each class. Index corresponds to
 	// Items[index - ClassOffset].
 	Icons [.IconCount]uint:8
-	// List of items.
+	// List of items. Libraries and their members follow the enum items.
 	Items [.ItemCount]Item
+	// For each item, bit N is set if the item exists in ChannelNames[N]. Index
+	// corresponds to index of Items. Present only if ChannelCount is not zero.
+	Channels [.ChannelCount > 0 ? .ItemCount : 0]uint:8
 	// List of item strings. Index corresponds to index of Items.
 	Strings [.ItemCount]String
+	// List of channel names.
+	ChannelNames [.ChannelCount]String
+	// For each class, the index of its superclass within the classes of
+	// Items, relative to ClassOffset. 0xFFFF if the class has no superclass.
+	// Index corresponds to Items[index - ClassOffset].
+	Superclasses [.IconCount]uint:16
 }
 
 String struct {
-	Size  uint:8
+	// Length of Value, encoded in groups of 7 bits, least significant group
+	// first. The high bit of each byte is set if more bytes follow.
+	Size  uvarint
 	Value [.Size]uint:8
 }
 
@@ -67,6 +81,12 @@ Item struct {
 	}
 	if .Type == Property {
 		Hidden bool:1
+	}
+	@7
+	// Whether the item is a library (with the Class type), or a member of a
+	// library (with the Property or Function type).
+	Library bool:1
+	if .Type == Property {
 		@8
 		ReadSecurity  Security
 		WriteSecurity Security
@@ -103,7 +123,17 @@ func writeDatabaseItem(v interface{}, removed bool) uint16 {
 	var data uint64
 
 	var typ int
-	switch v.(type) {
+	var library bool
+	switch v := v.(type) {
+	case *entities.Library:
+		typ = 0
+		library = true
+	case *entities.LibraryMember:
+		typ = 5
+		if v.MemberType == "Property" {
+			typ = 4
+		}
+		library = true
 	case *rbxapijson.Class:
 		typ = 0
 	case *rbxapijson.Enum:
@@ -123,6 +153,7 @@ func writeDatabaseItem(v interface{}, removed bool) uint16 {
 	}
 	data = binio.SetBits(data, 0, 3, typ)
 	data = binio.SetBit(data, 3, removed)
+	data = binio.SetBit(data, 7, library)
 
 	if v, ok := v.(rbxapi.Taggable); ok {
 		data = binio.SetBit(data, 4, v.GetTag("Deprecated"))
@@ -135,9 +166,10 @@ func writeDatabaseItem(v interface{}, removed bool) uint16 {
 		}
 	}
 
-	if v, ok := v.(interface{ GetSecurity() string }); ok {
+	switch v := v.(type) {
+	case interface{ GetSecurity() string }:
 		data = writeDatabaseSecurity(data, 8, v.GetSecurity())
-	} else if v, ok := v.(interface{ GetSecurity() (string, string) }); ok {
+	case interface{ GetSecurity() (string, string) }:
 		r, w := v.GetSecurity()
 		data = writeDatabaseSecurity(data, 8, r)
 		data = writeDatabaseSecurity(data, 11, w)
@@ -146,11 +178,43 @@ func writeDatabaseItem(v interface{}, removed bool) uint16 {
 	return uint16(data)
 }
 
+// searchDBVersion is the version of the search database format. It must be
+// incremented whenever the format changes.
+const searchDBVersion = 5
+
+// maxDatabaseChannels is the maximum number of release channels that can be
+// encoded in the search database.
+const maxDatabaseChannels = 8
+
+func writeDatabaseChannels(channels []string, list []string) uint8 {
+	var data uint64
+	for _, channel := range channels {
+		for i, name := range list {
+			if name == channel {
+				data = binio.SetBit(data, i, true)
+				break
+			}
+		}
+	}
+	return uint8(data)
+}
+
 func GenerateDatabase(w io.Writer, ent *entities.Entities) error {
+	if len(ent.ChannelList) > maxDatabaseChannels {
+		return fmt.Errorf("cannot encode more than %d channels", maxDatabaseChannels)
+	}
+
+	// Members that are hidden from the security context of the entities are
+	// omitted.
+	members := make([][]*entities.Member, len(ent.ClassList))
+	for i, class := range ent.ClassList {
+		members[i] = ent.VisibleMembers(class.MemberList)
+	}
+
 	bw := binio.NewWriter(w)
 
 	// Version
-	if !bw.Number(uint8(1)) {
+	if !bw.Number(uint8(searchDBVersion)) {
 		return bw.Err
 	}
 
@@ -167,17 +231,26 @@ func GenerateDatabase(w io.Writer, ent *entities.Entities) error {
 	}
 	items += len(ent.ClassList)
 	items += len(ent.EnumList)
-	for _, class := range ent.ClassList {
-		items += len(class.MemberList)
+	for _, list := range members {
+		items += len(list)
 	}
 	for _, enum := range ent.EnumList {
 		items += len(enum.ItemList)
 	}
+	items += len(ent.LibraryList)
+	for _, lib := range ent.LibraryList {
+		items += len(lib.MemberList)
+	}
 	// ItemCount
 	if !bw.Number(uint16(items)) {
 		return bw.Err
 	}
 
+	// ChannelCount
+	if !bw.Number(uint8(len(ent.ChannelList))) {
+		return bw.Err
+	}
+
 	// Icons
 	for _, class := range ent.ClassList {
 		var icon int
@@ -205,8 +278,8 @@ func GenerateDatabase(w io.Writer, ent *entities.Entities) error {
 			return bw.Err
 		}
 	}
-	for _, class := range ent.ClassList {
-		for _, member := range class.MemberList {
+	for _, list := range members {
+		for _, member := range list {
 			if !bw.Number(writeDatabaseItem(member.Element, member.Removed)) {
 				return bw.Err
 			}
@@ -219,6 +292,59 @@ func GenerateDatabase(w io.Writer, ent *entities.Entities) error {
 			}
 		}
 	}
+	for _, lib := range ent.LibraryList {
+		if !bw.Number(writeDatabaseItem(lib, lib.Removed)) {
+			return bw.Err
+		}
+	}
+	for _, lib := range ent.LibraryList {
+		for _, member := range lib.MemberList {
+			if !bw.Number(writeDatabaseItem(member, member.Removed)) {
+				return bw.Err
+			}
+		}
+	}
+
+	// Channels
+	if len(ent.ChannelList) > 0 {
+		for range ent.TypeList {
+			if !bw.Number(uint8(0)) {
+				return bw.Err
+			}
+		}
+		for _, class := range ent.ClassList {
+			if !bw.Number(writeDatabaseChannels(class.Channels, ent.ChannelList)) {
+				return bw.Err
+			}
+		}
+		for _, enum := range ent.EnumList {
+			if !bw.Number(writeDatabaseChannels(enum.Channels, ent.ChannelList)) {
+				return bw.Err
+			}
+		}
+		for _, list := range members {
+			for _, member := range list {
+				if !bw.Number(writeDatabaseChannels(member.Channels, ent.ChannelList)) {
+					return bw.Err
+				}
+			}
+		}
+		for _, enum := range ent.EnumList {
+			for _, item := range enum.ItemList {
+				if !bw.Number(writeDatabaseChannels(item.Channels, ent.ChannelList)) {
+					return bw.Err
+				}
+			}
+		}
+		// Libraries are not associated with channels.
+		for _, lib := range ent.LibraryList {
+			for i := 0; i <= len(lib.MemberList); i++ {
+				if !bw.Number(uint8(0)) {
+					return bw.Err
+				}
+			}
+		}
+	}
 
 	// Strings
 	for _, typ := range ent.TypeList {
@@ -236,8 +362,8 @@ func GenerateDatabase(w io.Writer, ent *entities.Entities) error {
 			return bw.Err
 		}
 	}
-	for _, class := range ent.ClassList {
-		for _, member := range class.MemberList {
+	for _, list := range members {
+		for _, member := range list {
 			if !bw.String(member.ID[0] + "." + member.ID[1]) {
 				return bw.Err
 			}
@@ -250,6 +376,42 @@ func GenerateDatabase(w io.Writer, ent *entities.Entities) error {
 			}
 		}
 	}
+	for _, lib := range ent.LibraryList {
+		if !bw.String(lib.ID) {
+			return bw.Err
+		}
+	}
+	for _, lib := range ent.LibraryList {
+		for _, member := range lib.MemberList {
+			if !bw.String(member.ID[0] + "." + member.ID[1]) {
+				return bw.Err
+			}
+		}
+	}
+
+	// ChannelNames
+	for _, name := range ent.ChannelList {
+		if !bw.String(name) {
+			return bw.Err
+		}
+	}
+
+	// Superclasses
+	classIndex := make(map[*entities.Class]int, len(ent.ClassList))
+	for i, class := range ent.ClassList {
+		classIndex[class] = i
+	}
+	for _, class := range ent.ClassList {
+		super := 0xFFFF
+		if len(class.Superclasses) > 0 {
+			if i, ok := classIndex[class.Superclasses[0]]; ok {
+				super = i
+			}
+		}
+		if !bw.Number(uint16(super)) {
+			return bw.Err
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/robloxapi/rbxapiref/apiext"
+	"github.com/robloxapi/rbxapiref/builds"
+)
+
+func init() {
+	AddCommand("snapshot", CommandInfo{
+		Description: "Write the API of a build, reconstructed from the manifest.",
+		Options: map[string]*flags.Option{
+			"output": &flags.Option{
+				Description: "Write to a file instead of standard output.",
+				ValueName:   "PATH",
+			},
+		},
+		Command: &SnapshotCommand{},
+	})
+}
+
+// SnapshotCommand writes the API dump of a build in JSON format. The build is
+// selected by the first argument, and is otherwise the latest build.
+type SnapshotCommand struct {
+	Output string `short:"o" long:"output"`
+}
+
+func (cmd *SnapshotCommand) Run(data *Data, args []string) (err error) {
+	patches := data.Manifest.Patches
+	if len(patches) == 0 {
+		return errors.New("manifest has no builds")
+	}
+	index := len(patches) - 1
+	if len(args) > 0 {
+		if index, err = builds.FindPatch(patches, args[0]); err != nil {
+			return err
+		}
+	}
+	root := builds.Snapshot(patches, index)
+	ext := builds.SnapshotExtension(patches, index)
+
+	var w io.Writer = os.Stdout
+	if cmd.Output != "" {
+		f, err := os.Create(cmd.Output)
+		if err != nil {
+			return fmt.Errorf("create snapshot: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	if err := apiext.Encode(bw, root, ext); err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	return bw.Flush()
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/robloxapi/rbxapi/rbxapijson"
 	"github.com/robloxapi/rbxapiref/builds"
 	"github.com/robloxapi/rbxapiref/entities"
+	"github.com/robloxapi/rbxapiref/query"
 )
 
 type listFilter struct {
@@ -73,6 +74,16 @@ func init() {
 	AddListFilter("Removed", func(v *entities.Type) bool { return v.Removed })
 	AddListFilter("Documented", func(v *entities.Type) bool { return v.Document != nil })
 
+	AddListFilter("Added", func(v *entities.Library) bool { return !v.Removed })
+	AddListFilter("Removed", func(v *entities.Library) bool { return v.Removed })
+	AddListFilter("Documented", func(v *entities.Library) bool { return v.Document != nil })
+
+	AddListFilter("Added", func(v *entities.LibraryMember) bool { return !v.Removed })
+	AddListFilter("Removed", func(v *entities.LibraryMember) bool { return v.Removed })
+	AddListFilter("ImplicitAdded", func(v *entities.LibraryMember) bool { return !v.Removed && !v.Parent.Removed })
+	AddListFilter("ImplicitRemoved", func(v *entities.LibraryMember) bool { return v.Removed || v.Parent.Removed })
+	AddListFilter("Documented", func(v *entities.LibraryMember) bool { return v.Document != nil })
+
 	AddListFilter("Class", func(v entities.ElementTyper) bool { return v.ElementType().Category == "Class" && !v.IsRemoved() })
 	AddListFilter("Enum", func(v entities.ElementTyper) bool { return v.ElementType().Category == "Enum" && !v.IsRemoved() })
 	AddListFilter("Type", func(v entities.ElementTyper) bool {
@@ -214,6 +225,9 @@ func TemplateFuncs(data *Data) template.FuncMap {
 		return template.HTML(buf.String()), err
 	}
 	funcs["filter"] = FilterList
+	funcs["query"] = func(q string) ([]interface{}, error) {
+		return query.Run(data.Entities, q)
+	}
 	funcs["history"] = data.GenerateHistoryElements
 	funcs["icon"] = data.Entities.Icon
 	funcs["istype"] = func(v interface{}, t string) bool {
@@ -366,6 +380,50 @@ func TemplateFuncs(data *Data) template.FuncMap {
 		}
 		return v.Interface()
 	}
+	// Removes members that are hidden from the security context of the
+	// entities.
+	funcs["visible"] = func(list interface{}) interface{} {
+		ents := data.Entities
+		if ents.SecurityContext == "" {
+			return list
+		}
+		switch src := list.(type) {
+		case []*entities.Member:
+			list = ents.VisibleMembers(src)
+		case []entities.Referrer:
+			dst := make([]entities.Referrer, 0, len(src))
+			for _, referrer := range src {
+				if ents.Visible(referrer.Member) {
+					dst = append(dst, referrer)
+				}
+			}
+			list = dst
+		case []entities.InheritedMembers:
+			dst := make([]entities.InheritedMembers, len(src))
+			for i, inherited := range src {
+				inherited.Members = ents.VisibleMembers(inherited.Members)
+				dst[i] = inherited
+			}
+			list = dst
+		case []entities.Anomaly:
+			dst := make([]entities.Anomaly, 0, len(src))
+			for _, anomaly := range src {
+				if ents.VisibleAnomaly(anomaly) {
+					dst = append(dst, anomaly)
+				}
+			}
+			list = dst
+		case []entities.ReportedAnomaly:
+			dst := make([]entities.ReportedAnomaly, 0, len(src))
+			for _, anomaly := range src {
+				if ents.VisibleAnomaly(anomaly.Anomaly) {
+					dst = append(dst, anomaly)
+				}
+			}
+			list = dst
+		}
+		return list
+	}
 
 	return funcs
 }
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/anaminus/but"
+	"github.com/jessevdk/go-flags"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+	"github.com/robloxapi/rbxapiref/builds"
+	"github.com/robloxapi/rbxapiref/fetch"
+)
+
+func init() {
+	AddCommand("verify", CommandInfo{
+		Description: "Compare the manifest against the actual API dumps of builds.",
+		Options: map[string]*flags.Option{
+			"all": &flags.Option{
+				Description: "Verify every build instead of a sample.",
+			},
+			"sample": &flags.Option{
+				Description: "The number of builds to verify, spread evenly across the history. Includes the first and latest builds.",
+				ValueName:   "N",
+			},
+		},
+		Command: &VerifyCommand{},
+	})
+}
+
+// VerifyCommand rebuilds snapshots of builds from the manifest, and compares
+// them against freshly fetched API dumps. The builds to verify may be given as
+// arguments. Otherwise, a sample of builds is verified, and the list of builds
+// in the manifest is compared against the list of builds fetched from the
+// configured locations.
+//
+// Each difference is reported on a separate line. An error is returned if any
+// differences are found, or if a build could not be verified.
+type VerifyCommand struct {
+	All    bool `long:"all"`
+	Sample int  `short:"n" long:"sample" default:"8"`
+}
+
+// sampleIndices returns n indices spread evenly between 0 and length-1,
+// inclusive.
+func sampleIndices(length, n int) []int {
+	if n >= length || n < 0 {
+		n = length
+	}
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		return []int{length - 1}
+	}
+	indices := make([]int, n)
+	for i := range indices {
+		indices[i] = i * (length - 1) / (n - 1)
+	}
+	return indices
+}
+
+// verifyBuildList compares the builds in the manifest against the builds
+// returned by the configured locations. Returns the number of differences.
+func (cmd *VerifyCommand) verifyBuildList(data *Data) (n int, err error) {
+	list, err := data.Settings.Build.Fetch()
+	if err != nil {
+		return 0, fmt.Errorf("fetch builds: %w", err)
+	}
+	patches := data.Manifest.Patches
+loopMissing:
+	for _, build := range list {
+		for _, patch := range patches {
+			if patch.Covers(build.Info) {
+				continue loopMissing
+			}
+		}
+		fmt.Printf("MISSING %s\n", build.Info)
+		n++
+	}
+	for _, patch := range patches {
+		infos := append([]builds.Info{patch.Info}, patch.Aliases...)
+	loopExtra:
+		for _, info := range infos {
+			for _, build := range list {
+				if build.Info.Equal(info) {
+					continue loopExtra
+				}
+			}
+			fmt.Printf("EXTRA %s\n", info)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (cmd *VerifyCommand) Run(data *Data, args []string) (err error) {
+	patches := data.Manifest.Patches
+	if len(patches) == 0 {
+		return errors.New("manifest has no builds")
+	}
+
+	var indices []int
+	var mismatches, failures int
+	if len(args) > 0 {
+		for _, arg := range args {
+			index, err := builds.FindPatch(patches, arg)
+			if err != nil {
+				return err
+			}
+			indices = append(indices, index)
+		}
+	} else {
+		if cmd.All {
+			indices = sampleIndices(len(patches), -1)
+		} else {
+			indices = sampleIndices(len(patches), cmd.Sample)
+		}
+		n, err := cmd.verifyBuildList(data)
+		if err != nil {
+			but.Log(err)
+			failures++
+		}
+		mismatches += n
+	}
+
+	// Replay patches incrementally, so that each patch is applied only once
+	// regardless of the number of builds being verified.
+	root := &rbxapijson.Root{}
+	ext := apiext.Root{}
+	next := 0
+	client := &fetch.Client{CacheMode: fetch.CacheTemp}
+	for _, index := range indices {
+		if index < next {
+			root = &rbxapijson.Root{}
+			ext = apiext.Root{}
+			next = 0
+		}
+		for ; next <= index; next++ {
+			builds.Replay(root, patches[next].Actions)
+			builds.ReplayExtension(ext, &patches[next])
+		}
+
+		patch := patches[index]
+		client.Config = data.Settings.Build.Configs[patch.Config]
+		actual, actualExt, err := client.APIDump(patch.Info.Hash)
+		if err != nil {
+			but.Logf("%s: %s\n", patch.Info, err)
+			failures++
+			continue
+		}
+		diff, discovered := builds.Compare(root, ext, actual, actualExt)
+		for _, action := range diff {
+			fmt.Printf("MISMATCH %s: %s\n", patch.Info, action.String())
+		}
+		// Extended fields reported by only one side are also mismatches.
+		n := len(diff)
+		for _, key := range discovered.Keys() {
+			fields := discovered[key]
+			names := fields.AllNames()
+			for _, name := range names {
+				if fields[name] == nil {
+					fmt.Printf("MISMATCH %s: unexpected field %s of %s\n", patch.Info, name, key)
+				} else {
+					fmt.Printf("MISMATCH %s: missing field %s of %s\n", patch.Info, name, key)
+				}
+				n++
+			}
+		}
+		if n == 0 {
+			fmt.Printf("OK %s\n", patch.Info)
+		}
+		mismatches += n
+	}
+
+	switch {
+	case mismatches > 0 && failures > 0:
+		return fmt.Errorf("found %d mismatches; %d failures", mismatches, failures)
+	case mismatches > 0:
+		return fmt.Errorf("found %d mismatches", mismatches)
+	case failures > 0:
+		return fmt.Errorf("%d failures", failures)
+	}
+	return nil
+}
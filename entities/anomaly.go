@@ -0,0 +1,300 @@
+package entities
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/robloxapi/rbxapiref/builds"
+)
+
+// AnomalyKind is a kind of questionable condition found in the API.
+type AnomalyKind int
+
+const (
+	// AnomalyRedeclared indicates that a class declares a member with the same
+	// name, member type, and signature as a member of a superclass.
+	AnomalyRedeclared AnomalyKind = iota
+	// AnomalyOverloaded indicates that a class declares a member with the same
+	// name as a member of a superclass, but with a different member type,
+	// parameters, return type, or value type.
+	AnomalyOverloaded
+	// AnomalyCaseCollision indicates that the names of members that apply to a
+	// class, or of items of an enum, differ only by case.
+	AnomalyCaseCollision
+	// AnomalyDuplicateValue indicates that items of an enum share a value.
+	AnomalyDuplicateValue
+)
+
+// AnomalyKinds lists each kind of anomaly, in order.
+var AnomalyKinds = []AnomalyKind{
+	AnomalyRedeclared,
+	AnomalyOverloaded,
+	AnomalyCaseCollision,
+	AnomalyDuplicateValue,
+}
+
+// ID returns an identifier for the kind, suitable for use as an anchor.
+func (k AnomalyKind) ID() string {
+	return strings.ToLower(strings.ReplaceAll(k.String(), " ", "-"))
+}
+
+func (k AnomalyKind) String() string {
+	switch k {
+	case AnomalyRedeclared:
+		return "Redeclared"
+	case AnomalyOverloaded:
+		return "Overloaded"
+	case AnomalyCaseCollision:
+		return "Case collision"
+	case AnomalyDuplicateValue:
+		return "Duplicate value"
+	}
+	return ""
+}
+
+// Anomaly is a questionable condition of a class or enum, found by analysis of
+// the current API. Removed entities are not analyzed.
+type Anomaly struct {
+	Kind AnomalyKind
+	// Elements lists the entities involved. Entities declared by the class or
+	// enum on which the anomaly is reported are first.
+	Elements []AnomalyElement
+	// Detail optionally describes the anomaly further, such as the fields by
+	// which a redeclared member differs.
+	Detail string
+}
+
+// AnomalyElement is an entity involved in an anomaly.
+type AnomalyElement struct {
+	// Ref refers to the entity.
+	Ref builds.Ref
+	// Entity is the *Member or *EnumItem referred to by Ref.
+	Entity Entity
+}
+
+// Name returns the name of the entity, qualified by the name of its parent.
+func (e AnomalyElement) Name() string {
+	return refName(e.Ref)
+}
+
+// LinkType returns the type of link to Entity, as accepted by
+// settings.Output.FileLink.
+func (e AnomalyElement) LinkType() string {
+	return linkType(e.Entity)
+}
+
+func anomalyElements(entities ...Entity) []AnomalyElement {
+	elements := make([]AnomalyElement, len(entities))
+	for i, entity := range entities {
+		elements[i] = AnomalyElement{Ref: refOf(entity), Entity: entity}
+	}
+	return elements
+}
+
+// signatureFields are the fields of a member that distinguish an overload
+// from a redeclaration.
+var signatureFields = map[string]bool{
+	"Parameters": true,
+	"ReturnType": true,
+	"ValueType":  true,
+}
+
+// compareMembers returns the sorted names of the fields, other than tags, by
+// which two members differ, and whether they have different signatures. The
+// fields of members of different types are not compared.
+func compareMembers(a, b *Member) (fields []string, overloaded bool) {
+	if a.Element.GetMemberType() != b.Element.GetMemberType() {
+		return nil, true
+	}
+	av := fieldValues(a.Element)
+	bv := fieldValues(b.Element)
+	for field, value := range av {
+		if field == "Tags" {
+			continue
+		}
+		if other := bv[field]; other == nil || other.String() != value.String() {
+			fields = append(fields, field)
+			if signatureFields[field] {
+				overloaded = true
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields, overloaded
+}
+
+// analyzeClass returns the anomalies of a current class, ordered by kind, then
+// by member name. The class hierarchy must already be built. Collisions
+// between inherited members are reported on the superclass that declares them
+// instead.
+func analyzeClass(eclass *Class) (anomalies []Anomaly) {
+	for _, emember := range eclass.MemberList {
+		if emember.Removed {
+			continue
+		}
+		name := emember.ID[1]
+		for _, inherited := range eclass.Inherited {
+			super := inherited.Class.Members[name]
+			if super == nil || super.Removed {
+				continue
+			}
+			anomaly := Anomaly{Kind: AnomalyRedeclared, Elements: anomalyElements(emember, super)}
+			fields, overloaded := compareMembers(emember, super)
+			switch {
+			case overloaded && len(fields) == 0:
+				anomaly.Kind = AnomalyOverloaded
+				anomaly.Detail = emember.Element.GetMemberType() + " replaces " + super.Element.GetMemberType()
+			case overloaded:
+				anomaly.Kind = AnomalyOverloaded
+				fallthrough
+			case len(fields) > 0:
+				anomaly.Detail = "Differs by " + strings.Join(fields, ", ")
+			}
+			anomalies = append(anomalies, anomaly)
+			break
+		}
+	}
+
+	groups := map[string][]*Member{}
+	var keys []string
+	for _, emember := range eclass.EffectiveMembers() {
+		key := strings.ToLower(emember.ID[1])
+		if groups[key] == nil {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], emember)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		// EffectiveMembers lists the members declared by the class first.
+		if group[0].Parent != eclass {
+			continue
+		}
+		elements := make([]Entity, len(group))
+		for i, emember := range group {
+			elements[i] = emember
+		}
+		anomalies = append(anomalies, Anomaly{Kind: AnomalyCaseCollision, Elements: anomalyElements(elements...)})
+	}
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return anomalies[i].Kind < anomalies[j].Kind
+	})
+	return anomalies
+}
+
+// analyzeEnum returns the anomalies of a current enum, ordered by kind. Items
+// are listed in the order of the enum's item list.
+func analyzeEnum(eenum *Enum) (anomalies []Anomaly) {
+	values := map[int][]Entity{}
+	var valueKeys []int
+	names := map[string][]Entity{}
+	var nameKeys []string
+	for _, eitem := range eenum.ItemList {
+		if eitem.Removed {
+			continue
+		}
+		value := eitem.Element.Value
+		if values[value] == nil {
+			valueKeys = append(valueKeys, value)
+		}
+		values[value] = append(values[value], eitem)
+		name := strings.ToLower(eitem.ID[1])
+		if names[name] == nil {
+			nameKeys = append(nameKeys, name)
+		}
+		names[name] = append(names[name], eitem)
+	}
+	for _, name := range nameKeys {
+		if group := names[name]; len(group) > 1 {
+			anomalies = append(anomalies, Anomaly{Kind: AnomalyCaseCollision, Elements: anomalyElements(group...)})
+		}
+	}
+	for _, value := range valueKeys {
+		if group := values[value]; len(group) > 1 {
+			anomalies = append(anomalies, Anomaly{
+				Kind:     AnomalyDuplicateValue,
+				Elements: anomalyElements(group...),
+				Detail:   "Value " + strconv.Itoa(value),
+			})
+		}
+	}
+	return anomalies
+}
+
+// buildAnomalies sets the anomalies of each current class and enum. The class
+// hierarchy must already be built.
+func (entities *Entities) buildAnomalies() {
+	forEach(len(entities.ClassList), func(i int) {
+		if eclass := entities.ClassList[i]; !eclass.Removed {
+			eclass.Anomalies = analyzeClass(eclass)
+		}
+	})
+	forEach(len(entities.EnumList), func(i int) {
+		if eenum := entities.EnumList[i]; !eenum.Removed {
+			eenum.Anomalies = analyzeEnum(eenum)
+		}
+	})
+}
+
+// ReportedAnomaly is an anomaly along with the class or enum on which it is
+// reported.
+type ReportedAnomaly struct {
+	Anomaly
+	// Entity is the *Class or *Enum.
+	Entity Entity
+}
+
+// LinkType returns the type of link to Entity, as accepted by
+// settings.Output.FileLink.
+func (r ReportedAnomaly) LinkType() string {
+	return linkType(r.Entity)
+}
+
+// AnomalyGroup lists the anomalies of one kind.
+type AnomalyGroup struct {
+	Kind AnomalyKind
+	List []ReportedAnomaly
+}
+
+// AnomalyReport returns the anomalies of every class and enum, grouped by
+// kind. A group is included for each kind, even if it is empty. Within a
+// group, anomalies are in the order of ClassList, then EnumList.
+func (entities *Entities) AnomalyReport() []AnomalyGroup {
+	groups := make([]AnomalyGroup, len(AnomalyKinds))
+	for i, kind := range AnomalyKinds {
+		groups[i].Kind = kind
+	}
+	for _, eclass := range entities.ClassList {
+		for _, anomaly := range eclass.Anomalies {
+			group := &groups[anomaly.Kind]
+			group.List = append(group.List, ReportedAnomaly{Anomaly: anomaly, Entity: eclass})
+		}
+	}
+	for _, eenum := range entities.EnumList {
+		for _, anomaly := range eenum.Anomalies {
+			group := &groups[anomaly.Kind]
+			group.List = append(group.List, ReportedAnomaly{Anomaly: anomaly, Entity: eenum})
+		}
+	}
+	return groups
+}
+
+// VisibleAnomaly returns whether an anomaly is displayed when the API is
+// viewed from the SecurityContext of the entities. An anomaly is omitted if
+// any member involved in it cannot be used from the context.
+func (entities *Entities) VisibleAnomaly(anomaly Anomaly) bool {
+	if entities.SecurityContext == "" {
+		return true
+	}
+	for _, element := range anomaly.Elements {
+		if member, ok := element.Entity.(*Member); ok && !entities.Visible(member) {
+			return false
+		}
+	}
+	return true
+}
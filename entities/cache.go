@@ -0,0 +1,186 @@
+package entities
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+	"github.com/robloxapi/rbxapiref/builds"
+)
+
+// CacheVersion is the version of the format of the entity cache. It must be
+// incremented whenever the format changes, or when the entities generated
+// from a list of patches would differ, so that stale caches are discarded.
+const CacheVersion = 3
+
+// CacheVersionError is returned by ReadCache when the cache was written with
+// a different version.
+type CacheVersionError int
+
+func (err CacheVersionError) Error() string {
+	return fmt.Sprintf("entity cache version %d, expected %d", int(err), CacheVersion)
+}
+
+func init() {
+	// Concrete types held by interfaces within patches and elements.
+	gob.Register(&rbxapijson.Property{})
+	gob.Register(&rbxapijson.Function{})
+	gob.Register(&rbxapijson.Event{})
+	gob.Register(&rbxapijson.Callback{})
+	gob.Register(rbxapijson.Type{})
+	gob.Register(rbxapijson.Parameters{})
+	gob.Register(rbxapijson.Tags{})
+}
+
+// cache is the state of entities that results from applying patches. The
+// derived state is regenerated by Update.
+type cache struct {
+	Version   int
+	Applied   []patchMark
+	Ext       apiext.Root
+	Classes   []cachedClass
+	Members   []cachedMember
+	Enums     []cachedEnum
+	EnumItems []cachedEnumItem
+}
+
+type cachedClass struct {
+	ID      string
+	Element *rbxapijson.Class
+	Patches []builds.Patch
+	Removed bool
+}
+
+type cachedMember struct {
+	ID      [2]string
+	Element rbxapi.Member
+	Patches []builds.Patch
+	Removed bool
+}
+
+type cachedEnum struct {
+	ID      string
+	Element *rbxapijson.Enum
+	Patches []builds.Patch
+	Removed bool
+}
+
+type cachedEnumItem struct {
+	ID      [2]string
+	Element *rbxapijson.EnumItem
+	Patches []builds.Patch
+	Removed bool
+}
+
+// WriteCache writes the history of each entity to w, along with the patches
+// that have been applied. Channel-only entities are not written. Entities read
+// back with ReadCache can be updated with only the patches that follow.
+func (entities *Entities) WriteCache(w io.Writer) error {
+	c := cache{
+		Version:   CacheVersion,
+		Applied:   entities.applied,
+		Ext:       entities.ext,
+		Classes:   make([]cachedClass, 0, len(entities.Classes)),
+		Members:   make([]cachedMember, 0, len(entities.Members)),
+		Enums:     make([]cachedEnum, 0, len(entities.Enums)),
+		EnumItems: make([]cachedEnumItem, 0, len(entities.EnumItems)),
+	}
+	for _, e := range entities.Classes {
+		if e.ChannelOnly {
+			continue
+		}
+		c.Classes = append(c.Classes, cachedClass{e.ID, e.Element, e.Patches, e.Removed})
+	}
+	for _, e := range entities.Members {
+		if e.ChannelOnly {
+			continue
+		}
+		c.Members = append(c.Members, cachedMember{e.ID, e.Element, e.Patches, e.Removed})
+	}
+	for _, e := range entities.Enums {
+		if e.ChannelOnly {
+			continue
+		}
+		c.Enums = append(c.Enums, cachedEnum{e.ID, e.Element, e.Patches, e.Removed})
+	}
+	for _, e := range entities.EnumItems {
+		if e.ChannelOnly {
+			continue
+		}
+		c.EnumItems = append(c.EnumItems, cachedEnumItem{e.ID, e.Element, e.Patches, e.Removed})
+	}
+	if err := gob.NewEncoder(w).Encode(&c); err != nil {
+		return fmt.Errorf("encode entity cache: %w", err)
+	}
+	return nil
+}
+
+// ReadCache reads entities written by WriteCache. The entities must be
+// updated with Update before they are used. Returns a CacheVersionError if the
+// cache was written with a different version.
+func ReadCache(r io.Reader) (*Entities, error) {
+	var c cache
+	if err := gob.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("decode entity cache: %w", err)
+	}
+	if c.Version != CacheVersion {
+		return nil, CacheVersionError(c.Version)
+	}
+	entities := NewEntities()
+	entities.applied = c.Applied
+	if c.Ext != nil {
+		entities.ext = c.Ext
+	}
+	for _, e := range c.Classes {
+		entities.Classes[e.ID] = &Class{
+			ID:      e.ID,
+			Element: e.Element,
+			Patches: e.Patches,
+			Removed: e.Removed,
+			Members: map[string]*Member{},
+		}
+	}
+	for _, e := range c.Members {
+		parent := entities.Classes[e.ID[0]]
+		if parent == nil {
+			return nil, fmt.Errorf("decode entity cache: missing class of member %s.%s", e.ID[0], e.ID[1])
+		}
+		emember := &Member{
+			ID:      e.ID,
+			Element: e.Element,
+			Patches: e.Patches,
+			Removed: e.Removed,
+			Parent:  parent,
+		}
+		parent.Members[e.ID[1]] = emember
+		entities.Members[e.ID] = emember
+	}
+	for _, e := range c.Enums {
+		entities.Enums[e.ID] = &Enum{
+			ID:      e.ID,
+			Element: e.Element,
+			Patches: e.Patches,
+			Removed: e.Removed,
+			Items:   map[string]*EnumItem{},
+		}
+	}
+	for _, e := range c.EnumItems {
+		parent := entities.Enums[e.ID[0]]
+		if parent == nil {
+			return nil, fmt.Errorf("decode entity cache: missing enum of item %s.%s", e.ID[0], e.ID[1])
+		}
+		eitem := &EnumItem{
+			ID:      e.ID,
+			Element: e.Element,
+			Patches: e.Patches,
+			Removed: e.Removed,
+			Parent:  parent,
+		}
+		parent.Items[e.ID[1]] = eitem
+		entities.EnumItems[e.ID] = eitem
+	}
+	return entities, nil
+}
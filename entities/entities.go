@@ -11,6 +11,7 @@ import (
 	"github.com/robloxapi/rbxapi"
 	"github.com/robloxapi/rbxapi/patch"
 	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
 	"github.com/robloxapi/rbxapiref/builds"
 	"github.com/robloxapi/rbxapiref/documents"
 	"github.com/robloxapi/rbxfile"
@@ -30,7 +31,30 @@ type Entities struct {
 	TypeList []*Type
 	TypeCats []TypeCategory
 
+	// Libraries maps the name of each library added with AddLibraries to the
+	// library. LibraryList is sorted by name.
+	Libraries   map[string]*Library
+	LibraryList []*Library
+
 	Coverage float32
+
+	// ChannelList is the list of release channels set with SetChannels, in
+	// order.
+	ChannelList []string
+
+	// SecurityContext is the security context from which the API is viewed.
+	// When set, members that cannot be used from the context are hidden, and
+	// entities that can only partially be used are marked. Empty to view the
+	// entire API.
+	SecurityContext string
+
+	// channels is the current API of each release channel.
+	channels []ChannelAPI
+	// applied marks each patch that has been applied by Update, in order.
+	applied []patchMark
+	// ext holds the extended fields of each element, as of the last applied
+	// patch. Removed elements retain their last known fields.
+	ext apiext.Root
 }
 
 func (e *Entities) CoverageString() string {
@@ -52,7 +76,14 @@ func (e *Entities) ElementStatusClasses(suffix bool, v ...interface{}) string {
 	var t rbxapi.Taggable
 	var action *builds.Action
 	var removed bool
+	access := AccessFull
 	switch value := v[0].(type) {
+	case *Library:
+		removed = value.Removed
+		t = value
+	case *LibraryMember:
+		removed = value.Removed
+		t = value
 	case rbxapi.Taggable:
 		t = value
 	case string:
@@ -63,6 +94,7 @@ func (e *Entities) ElementStatusClasses(suffix bool, v ...interface{}) string {
 				return ""
 			}
 			removed = class.Removed
+			access = class.Access(e.SecurityContext)
 			t = class.Element
 		case "member":
 			class, ok := e.Classes[v[1].(string)]
@@ -74,6 +106,7 @@ func (e *Entities) ElementStatusClasses(suffix bool, v ...interface{}) string {
 				return ""
 			}
 			removed = member.Removed
+			access = member.Access(e.SecurityContext)
 			t = member.Element
 		case "enum":
 			enum, ok := e.Enums[v[1].(string)]
@@ -98,9 +131,11 @@ func (e *Entities) ElementStatusClasses(suffix bool, v ...interface{}) string {
 		}
 	case *Class:
 		removed = value.Removed
+		access = value.Access(e.SecurityContext)
 		t = value.Element
 	case *Member:
 		removed = value.Removed
+		access = value.Access(e.SecurityContext)
 		t = value.Element
 	case *Enum:
 		removed = value.Removed
@@ -166,6 +201,12 @@ func (e *Entities) ElementStatusClasses(suffix bool, v ...interface{}) string {
 	if removed {
 		s = append(s, "api-removed")
 	}
+	switch access {
+	case AccessNone:
+		s = append(s, "api-access-none")
+	case AccessPartial:
+		s = append(s, "api-access-partial")
+	}
 	switch m := t.(type) {
 	case interface{ GetSecurity() (string, string) }:
 		r, w := m.GetSecurity()
@@ -275,6 +316,14 @@ retry:
 		class = "member-icon"
 		title = "TypeCategory"
 		index = 0
+	case *Library:
+		class = "member-icon"
+		title = "Library"
+		index = 0
+	case *LibraryMember:
+		class = "member-icon"
+		title = value.MemberType
+		index = memberIconIndex[title]
 	case *Type:
 		class = "member-icon"
 		title = "Type"
@@ -434,12 +483,32 @@ type Class struct {
 	Element *rbxapijson.Class
 	Patches []builds.Patch
 	Removed bool
+	// Channels lists the release channels in which the element currently
+	// exists.
+	Channels []string
+	// ChannelOnly is whether the element exists only in release channels, and
+	// not in the history of the main channel. Such an entity has no patches.
+	ChannelOnly bool
+	// Timeline records the values held by each field of the element over
+	// time.
+	Timeline Timeline
+	// Extension holds the most recently known extended fields of the element.
+	Extension apiext.Fields
+	// Removal records the history of the entity if it is removed. Nil if the
+	// entity is current.
+	Removal *Removal
 
 	Superclasses []*Class
 	Subclasses   []*Class
 
 	Members    map[string]*Member
 	MemberList []*Member
+	// Inherited lists the current members inherited from each superclass,
+	// ordered from the nearest superclass to the farthest.
+	Inherited []InheritedMembers
+	// Anomalies lists the questionable conditions found in the members of
+	// the class. Nil if the class is removed.
+	Anomalies []Anomaly
 
 	References    map[rbxapijson.Type]ElementTyper
 	ReferenceList []ElementTyper
@@ -451,6 +520,87 @@ type Class struct {
 	Metadata  Metadata
 }
 
+// InheritedMembers is the list of members that a class inherits from one of
+// its superclasses.
+type InheritedMembers struct {
+	// Class is the superclass that declares the members.
+	Class *Class
+	// Members is the list of current members declared by Class, in the order
+	// of Class.MemberList.
+	Members []*Member
+	// Shadowed maps the name of each member in Members that is shadowed to the
+	// member that shadows it. A member is shadowed when the inheriting class,
+	// or a superclass nearer to it, declares a current member of the same
+	// name.
+	Shadowed map[string]*Member
+}
+
+// EffectiveMembers returns the current members of the class, including each
+// inherited member that is not shadowed. The class's own members are first,
+// followed by the members of each superclass, from nearest to farthest.
+func (e *Class) EffectiveMembers() []*Member {
+	var members []*Member
+	for _, member := range e.MemberList {
+		if !member.Removed {
+			members = append(members, member)
+		}
+	}
+	for _, inherited := range e.Inherited {
+		for _, member := range inherited.Members {
+			if inherited.Shadowed[member.ID[1]] == nil {
+				members = append(members, member)
+			}
+		}
+	}
+	return members
+}
+
+// ResolveMember returns the current member of the given name that applies to
+// the class: either the class's own member, or the member inherited from the
+// nearest superclass. Returns nil if there is no such member.
+func (e *Class) ResolveMember(name string) *Member {
+	if member := e.Members[name]; member != nil && !member.Removed {
+		return member
+	}
+	for _, inherited := range e.Inherited {
+		if member := inherited.Class.Members[name]; member != nil && !member.Removed {
+			return member
+		}
+	}
+	return nil
+}
+
+// resolveInherited sets the inherited members of the class. Superclasses and
+// the member list of each class must already be set.
+func (e *Class) resolveInherited() {
+	e.Inherited = e.Inherited[:0]
+	declared := map[string]*Member{}
+	for _, member := range e.MemberList {
+		if !member.Removed {
+			declared[member.ID[1]] = member
+		}
+	}
+	for _, super := range e.Superclasses {
+		inherited := InheritedMembers{Class: super}
+		for _, member := range super.MemberList {
+			if member.Removed {
+				continue
+			}
+			name := member.ID[1]
+			if shadow := declared[name]; shadow != nil {
+				if inherited.Shadowed == nil {
+					inherited.Shadowed = map[string]*Member{}
+				}
+				inherited.Shadowed[name] = shadow
+			} else {
+				declared[name] = member
+			}
+			inherited.Members = append(inherited.Members, member)
+		}
+		e.Inherited = append(e.Inherited, inherited)
+	}
+}
+
 func (e *Class) IsRemoved() bool    { return e.Removed }
 func (e *Class) Identifier() string { return e.ID }
 func (e *Class) ElementType() rbxapijson.Type {
@@ -460,10 +610,21 @@ func (e *Class) GetDocument() Document   { return e.Document }
 func (e *Class) GetDocStatus() DocStatus { return e.DocStatus }
 
 type Member struct {
-	ID      [2]string
-	Element rbxapi.Member
-	Patches []builds.Patch
-	Removed bool
+	ID       [2]string
+	Element  rbxapi.Member
+	Patches  []builds.Patch
+	Removed  bool
+	Channels []string
+	// ChannelOnly is whether the element exists only in release channels.
+	ChannelOnly bool
+	// Timeline records the values held by each field of the element over
+	// time.
+	Timeline Timeline
+	// Extension holds the most recently known extended fields of the element.
+	Extension apiext.Fields
+	// Removal records the history of the entity if it is removed. Nil if the
+	// entity is current.
+	Removal *Removal
 
 	Parent *Class
 
@@ -480,13 +641,27 @@ func (e *Member) GetDocument() Document   { return e.Document }
 func (e *Member) GetDocStatus() DocStatus { return e.DocStatus }
 
 type Enum struct {
-	ID      string
-	Element *rbxapijson.Enum
-	Patches []builds.Patch
-	Removed bool
+	ID       string
+	Element  *rbxapijson.Enum
+	Patches  []builds.Patch
+	Removed  bool
+	Channels []string
+	// ChannelOnly is whether the element exists only in release channels.
+	ChannelOnly bool
+	// Timeline records the values held by each field of the element over
+	// time.
+	Timeline Timeline
+	// Extension holds the most recently known extended fields of the element.
+	Extension apiext.Fields
+	// Removal records the history of the entity if it is removed. Nil if the
+	// entity is current.
+	Removal *Removal
 
 	Items    map[string]*EnumItem
 	ItemList []*EnumItem
+	// Anomalies lists the questionable conditions found in the items of the
+	// enum. Nil if the enum is removed.
+	Anomalies []Anomaly
 
 	Referrers    map[[2]string]Referrer
 	ReferrerList []Referrer
@@ -505,10 +680,18 @@ func (e *Enum) GetDocument() Document   { return e.Document }
 func (e *Enum) GetDocStatus() DocStatus { return e.DocStatus }
 
 type EnumItem struct {
-	ID      [2]string
-	Element *rbxapijson.EnumItem
-	Patches []builds.Patch
-	Removed bool
+	ID       [2]string
+	Element  *rbxapijson.EnumItem
+	Patches  []builds.Patch
+	Removed  bool
+	Channels []string
+	// ChannelOnly is whether the element exists only in release channels.
+	ChannelOnly bool
+	// Timeline records the values held by each field of the element over
+	// time.
+	Timeline Timeline
+	// Extension holds the most recently known extended fields of the element.
+	Extension apiext.Fields
 
 	Parent *Enum
 
@@ -555,20 +738,91 @@ type Referrer struct {
 	Parameter *rbxapijson.Parameter
 }
 
-func addPatch(patches *[]builds.Patch, action *builds.Action, info builds.Info) {
+// entityPatch returns the patch in patches that corresponds to src, appending
+// a new patch if there is none.
+func entityPatch(patches *[]builds.Patch, src *builds.Patch) *builds.Patch {
 	for i := len(*patches) - 1; i >= 0; i-- {
-		if (*patches)[i].Info.Equal(info) {
-			(*patches)[i].Actions = append((*patches)[i].Actions, *action)
-			return
+		if (*patches)[i].Info.Equal(src.Info) {
+			return &(*patches)[i]
 		}
 	}
 	*patches = append(*patches, builds.Patch{
-		Info:    info,
-		Actions: []builds.Action{*action},
+		Info:       src.Info,
+		Unreleased: src.Unreleased,
 	})
+	return &(*patches)[len(*patches)-1]
+}
+
+func addPatch(patches *[]builds.Patch, action *builds.Action, src *builds.Patch) {
+	p := entityPatch(patches, src)
+	p.Actions = append(p.Actions, *action)
+}
+
+// AddMetadata adds a change to the reflection metadata of an element to the
+// history of the element's entity. The change is ignored if the entity does
+// not exist or is removed.
+func (entities *Entities) AddMetadata(action *builds.MetadataAction, src *builds.Patch) {
+	var patches *[]builds.Patch
+	element := action.Element
+	switch element.Type {
+	case "Class":
+		if e := entities.Classes[element.Name]; e != nil && !e.Removed {
+			patches = &e.Patches
+		}
+	case "Member":
+		if e := entities.Members[[2]string{element.Parent, element.Name}]; e != nil && !e.Removed {
+			patches = &e.Patches
+		}
+	case "Enum":
+		if e := entities.Enums[element.Name]; e != nil && !e.Removed {
+			patches = &e.Patches
+		}
+	case "EnumItem":
+		if e := entities.EnumItems[[2]string{element.Parent, element.Name}]; e != nil && !e.Removed {
+			patches = &e.Patches
+		}
+	}
+	if patches == nil {
+		return
+	}
+	p := entityPatch(patches, src)
+	p.Metadata = append(p.Metadata, *action)
+}
+
+// AddExtension adds the extended fields of an element that became known in a
+// build to the history of the element's entity. Fields are ignored if the
+// entity does not exist or is removed.
+func (entities *Entities) AddExtension(key apiext.Key, fields apiext.Fields, src *builds.Patch) {
+	var patches *[]builds.Patch
+	switch key.Type {
+	case "Class":
+		if e := entities.Classes[key.Name]; e != nil && !e.Removed {
+			patches = &e.Patches
+		}
+	case "Member":
+		if e := entities.Members[[2]string{key.Parent, key.Name}]; e != nil && !e.Removed {
+			patches = &e.Patches
+		}
+	case "Enum":
+		if e := entities.Enums[key.Name]; e != nil && !e.Removed {
+			patches = &e.Patches
+		}
+	case "EnumItem":
+		if e := entities.EnumItems[[2]string{key.Parent, key.Name}]; e != nil && !e.Removed {
+			patches = &e.Patches
+		}
+	}
+	if patches == nil {
+		return
+	}
+	p := entityPatch(patches, src)
+	if p.Extension == nil {
+		p.Extension = apiext.Root{}
+	}
+	p.Extension[key] = fields.Copy()
 }
 
-func (entities *Entities) AddClass(action *builds.Action, info builds.Info) {
+func (entities *Entities) AddClass(action *builds.Action, src *builds.Patch) {
 	class := action.Class
 	id := class.Name
 	eclass := entities.Classes[id]
@@ -615,7 +869,7 @@ func (entities *Entities) AddClass(action *builds.Action, info builds.Info) {
 					p := eclass.Patches[len(eclass.Patches)-1]
 					// TODO: Is it possible for an entity patch which removes
 					// the entity to have any action other than the removal?
-					addPatch(&emember.Patches, &p.Actions[0], p.Info)
+					addPatch(&emember.Patches, &p.Actions[0], &p)
 				}
 			}
 			for _, member := range class.Members {
@@ -625,7 +879,7 @@ func (entities *Entities) AddClass(action *builds.Action, info builds.Info) {
 				}
 				if eclass.Element.GetMember(member.GetName()) == nil {
 					// Include the patch that adds the member.
-					addPatch(&emember.Patches, action, info)
+					addPatch(&emember.Patches, action, src)
 				}
 			}
 		}
@@ -636,10 +890,10 @@ func (entities *Entities) AddClass(action *builds.Action, info builds.Info) {
 	case patch.Change:
 		eclass.Element.Patch([]patch.Action{action})
 	}
-	addPatch(&eclass.Patches, action, info)
+	addPatch(&eclass.Patches, action, src)
 }
 
-func (entities *Entities) AddMember(action *builds.Action, info builds.Info) {
+func (entities *Entities) AddMember(action *builds.Action, src *builds.Patch) {
 	class := action.Class
 	member := action.GetMember()
 	id := [2]string{class.Name, member.GetName()}
@@ -658,7 +912,7 @@ func (entities *Entities) AddMember(action *builds.Action, info builds.Info) {
 		eclass.Members[id[1]] = emember
 		entities.Members[id] = emember
 	}
-	addPatch(&emember.Patches, action, info)
+	addPatch(&emember.Patches, action, src)
 	eclass.Element.Patch([]patch.Action{patch.Member(action)})
 	switch action.Type {
 	case patch.Add:
@@ -671,7 +925,7 @@ func (entities *Entities) AddMember(action *builds.Action, info builds.Info) {
 	}
 }
 
-func (entities *Entities) AddEnum(action *builds.Action, info builds.Info) {
+func (entities *Entities) AddEnum(action *builds.Action, src *builds.Patch) {
 	enum := action.Enum
 	id := enum.Name
 	eenum := entities.Enums[id]
@@ -714,7 +968,7 @@ func (entities *Entities) AddEnum(action *builds.Action, info builds.Info) {
 						continue
 					}
 					p := eenum.Patches[len(eenum.Patches)-1]
-					addPatch(&eitem.Patches, &p.Actions[0], p.Info)
+					addPatch(&eitem.Patches, &p.Actions[0], &p)
 				}
 			}
 		}
@@ -725,10 +979,10 @@ func (entities *Entities) AddEnum(action *builds.Action, info builds.Info) {
 	case patch.Change:
 		eenum.Element.Patch([]patch.Action{action})
 	}
-	addPatch(&eenum.Patches, action, info)
+	addPatch(&eenum.Patches, action, src)
 }
 
-func (entities *Entities) AddEnumItem(action *builds.Action, info builds.Info) {
+func (entities *Entities) AddEnumItem(action *builds.Action, src *builds.Patch) {
 	enum := action.Enum
 	item := action.EnumItem
 	id := [2]string{enum.Name, item.Name}
@@ -746,7 +1000,7 @@ func (entities *Entities) AddEnumItem(action *builds.Action, info builds.Info) {
 		eenum.Items[id[1]] = eitem
 		entities.EnumItems[id] = eitem
 	}
-	addPatch(&eitem.Patches, action, info)
+	addPatch(&eitem.Patches, action, src)
 	eenum.Element.Patch([]patch.Action{action})
 	switch action.Type {
 	case patch.Add:
@@ -766,6 +1020,10 @@ func (entities *Entities) ListAll() []interface{} {
 	n += len(entities.EnumItems)
 	n += len(entities.TypeCats)
 	n += len(entities.Types)
+	n += len(entities.LibraryList)
+	for _, lib := range entities.LibraryList {
+		n += len(lib.MemberList)
+	}
 	all := make([]interface{}, 0, n)
 
 	var addClasses func(classes []*Class)
@@ -794,6 +1052,13 @@ func (entities *Entities) ListAll() []interface{} {
 		}
 	}
 
+	for _, lib := range entities.LibraryList {
+		all = append(all, lib)
+		for _, member := range lib.MemberList {
+			all = append(all, member)
+		}
+	}
+
 	return all
 }
 
@@ -804,330 +1069,87 @@ var memberTypeOrder = map[string]int{
 	"Callback": 3,
 }
 
-func GenerateEntities(patches []builds.Patch) (entities *Entities) {
-	entities = &Entities{
-		Classes:   make(map[string]*Class),
-		Members:   make(map[[2]string]*Member),
-		Enums:     make(map[string]*Enum),
-		EnumItems: make(map[[2]string]*EnumItem),
-		Types:     make(map[string]*Type),
-	}
+// ChannelAPI is the current API of a release channel.
+type ChannelAPI struct {
+	Name string
+	Root *rbxapijson.Root
+}
 
-	for _, patch := range patches {
-		for _, action := range patch.Actions {
-			switch {
-			case action.EnumItem != nil:
-				entities.AddEnumItem(&action, patch.Info)
-			case action.Enum != nil:
-				entities.AddEnum(&action, patch.Info)
-			case action.GetMember() != nil:
-				entities.AddMember(&action, patch.Info)
-			case action.Class != nil:
-				entities.AddClass(&action, patch.Info)
-			}
-		}
-	}
+// SetChannels sets the release channels of the entities, then regenerates the
+// derived state of each entity as with Update. The channels are kept by later
+// updates. Like Update, curated additions such as replacements and libraries
+// are discarded, and must be added again.
+func (entities *Entities) SetChannels(channels []ChannelAPI) {
+	entities.channels = channels
+	entities.index()
+}
 
-	referType := func(referrer Referrer, typ rbxapijson.Type, current bool) {
-		var et ElementTyper
-		switch typ.Category {
-		case "Class":
-			if !current {
-				return
-			}
-			if referrer.Member.Removed {
-				return
-			}
-			eclass := entities.Classes[typ.Name]
+// buildChannels records the presence of each entity in each release channel.
+// An element of a channel that has no entity is given a channel-only entity,
+// which is discarded by reset.
+func (entities *Entities) buildChannels() {
+	for _, channel := range entities.channels {
+		name := channel.Name
+		entities.ChannelList = append(entities.ChannelList, name)
+		for _, class := range channel.Root.Classes {
+			eclass := entities.Classes[class.Name]
 			if eclass == nil {
-				return
-			}
-			if _, ok := eclass.Referrers[referrer.Member.ID]; !ok {
-				eclass.Referrers[referrer.Member.ID] = referrer
-				eclass.ReferrerList = append(eclass.ReferrerList, referrer)
-			}
-			et = eclass
-		case "Enum":
-			if !current {
-				return
-			}
-			if referrer.Member.Removed {
-				return
-			}
-			eenum := entities.Enums[typ.Name]
-			if eenum == nil {
-				return
-			}
-			if _, ok := eenum.Referrers[referrer.Member.ID]; !ok {
-				eenum.Referrers[referrer.Member.ID] = referrer
-				eenum.ReferrerList = append(eenum.ReferrerList, referrer)
-			}
-			et = eenum
-		default:
-			etype := entities.Types[typ.Name]
-			if etype == nil {
-				etype = &Type{
-					ID:          typ.Name,
-					Element:     typ,
-					Removed:     true,
+				eclass = &Class{
+					ID:          class.Name,
+					Element:     class.Copy().(*rbxapijson.Class),
+					Members:     map[string]*Member{},
+					References:  map[rbxapijson.Type]ElementTyper{},
 					Referrers:   map[[2]string]Referrer{},
-					RemovedRefs: map[[2]string]Referrer{},
-				}
-				entities.Types[typ.Name] = etype
-			}
-			if !current || referrer.Member.Removed || referrer.Member.Parent.Removed {
-				if _, ok := etype.RemovedRefs[referrer.Member.ID]; !ok {
-					etype.RemovedRefs[referrer.Member.ID] = referrer
-					etype.RemovedRefList = append(etype.RemovedRefList, referrer)
-				}
-			}
-			if !current {
-				return
-			}
-			if referrer.Member.Removed {
-				return
-			}
-			if !referrer.Member.Removed && !referrer.Member.Parent.Removed {
-				etype.Removed = false
-			}
-			if _, ok := etype.Referrers[referrer.Member.ID]; !ok {
-				etype.Referrers[referrer.Member.ID] = referrer
-				etype.ReferrerList = append(etype.ReferrerList, referrer)
-			}
-			et = etype
-		}
-		if referrer.Member.References[typ] == nil {
-			referrer.Member.References[typ] = et
-			referrer.Member.ReferenceList = append(referrer.Member.ReferenceList, et)
-		}
-	}
-
-	for _, entity := range entities.Members {
-		switch element := entity.Element.(type) {
-		case *rbxapijson.Property:
-			referType(Referrer{entity, nil}, element.ValueType, true)
-			for _, p := range entity.Patches {
-				for _, a := range p.Actions {
-					member := a.Property
-					if member == nil {
-						continue
-					}
-					referType(Referrer{entity, nil}, member.ValueType, false)
+					ChannelOnly: true,
 				}
+				entities.Classes[eclass.ID] = eclass
 			}
-		case *rbxapijson.Function:
-			referType(Referrer{entity, nil}, element.ReturnType, true)
-			for i, param := range element.Parameters {
-				referType(Referrer{entity, &element.Parameters[i]}, param.Type, true)
-			}
-			for _, p := range entity.Patches {
-				for _, a := range p.Actions {
-					member := a.Function
-					if member == nil {
-						continue
-					}
-					referType(Referrer{entity, nil}, member.ReturnType, false)
-					for i, param := range member.Parameters {
-						referType(Referrer{entity, &member.Parameters[i]}, param.Type, false)
-					}
-				}
-			}
-		case *rbxapijson.Event:
-			for i, param := range element.Parameters {
-				referType(Referrer{entity, &element.Parameters[i]}, param.Type, true)
-			}
-			for _, p := range entity.Patches {
-				for _, a := range p.Actions {
-					member := a.Event
-					if member == nil {
-						continue
-					}
-					for i, param := range member.Parameters {
-						referType(Referrer{entity, &member.Parameters[i]}, param.Type, false)
-					}
-				}
-			}
-		case *rbxapijson.Callback:
-			referType(Referrer{entity, nil}, element.ReturnType, true)
-			for i, param := range element.Parameters {
-				referType(Referrer{entity, &element.Parameters[i]}, param.Type, true)
-			}
-			for _, p := range entity.Patches {
-				for _, a := range p.Actions {
-					member := a.Callback
-					if member == nil {
-						continue
-					}
-					referType(Referrer{entity, nil}, member.ReturnType, false)
-					for i, param := range member.Parameters {
-						referType(Referrer{entity, &member.Parameters[i]}, param.Type, false)
+			eclass.Channels = append(eclass.Channels, name)
+			for _, member := range class.Members {
+				id := [2]string{class.Name, member.GetName()}
+				emember := eclass.Members[id[1]]
+				if emember == nil {
+					emember = &Member{
+						ID:          id,
+						Element:     member.Copy(),
+						References:  map[rbxapijson.Type]ElementTyper{},
+						Parent:      eclass,
+						ChannelOnly: true,
 					}
+					eclass.Members[id[1]] = emember
+					entities.Members[id] = emember
 				}
+				emember.Channels = append(emember.Channels, name)
 			}
 		}
-	}
-
-	for _, eclass := range entities.Classes {
-		for _, emember := range eclass.Members {
-			sort.Slice(emember.ReferenceList, func(i, j int) bool {
-				it := emember.ReferenceList[i].ElementType()
-				jt := emember.ReferenceList[j].ElementType()
-				if it.Category == jt.Category {
-					return it.Name < jt.Name
-				}
-				return it.Category < jt.Category
-			})
-			for _, et := range emember.ReferenceList {
-				if typ := et.ElementType(); eclass.References[typ] == nil {
-					eclass.References[typ] = et
-					eclass.ReferenceList = append(eclass.ReferenceList, et)
-				}
-			}
-		}
-		sort.Slice(eclass.ReferenceList, func(i, j int) bool {
-			it := eclass.ReferenceList[i].ElementType()
-			jt := eclass.ReferenceList[j].ElementType()
-			if it.Category == jt.Category {
-				return it.Name < jt.Name
-			}
-			return it.Category < jt.Category
-		})
-		sort.Slice(eclass.ReferrerList, func(i, j int) bool {
-			if eclass.ReferrerList[i].Member.ID[0] == eclass.ReferrerList[j].Member.ID[0] {
-				return eclass.ReferrerList[i].Member.ID[1] < eclass.ReferrerList[j].Member.ID[1]
-			}
-			return eclass.ReferrerList[i].Member.ID[0] < eclass.ReferrerList[j].Member.ID[0]
-		})
-	}
-
-	for _, eenum := range entities.Enums {
-		sort.Slice(eenum.ReferrerList, func(i, j int) bool {
-			if eenum.ReferrerList[i].Member.ID[0] == eenum.ReferrerList[j].Member.ID[0] {
-				return eenum.ReferrerList[i].Member.ID[1] < eenum.ReferrerList[j].Member.ID[1]
-			}
-			return eenum.ReferrerList[i].Member.ID[0] < eenum.ReferrerList[j].Member.ID[0]
-		})
-	}
-
-	for _, etype := range entities.Types {
-		sort.Slice(etype.ReferrerList, func(i, j int) bool {
-			if etype.ReferrerList[i].Member.ID[0] == etype.ReferrerList[j].Member.ID[0] {
-				return etype.ReferrerList[i].Member.ID[1] < etype.ReferrerList[j].Member.ID[1]
-			}
-			return etype.ReferrerList[i].Member.ID[0] < etype.ReferrerList[j].Member.ID[0]
-		})
-		sort.Slice(etype.RemovedRefList, func(i, j int) bool {
-			if etype.RemovedRefList[i].Member.ID[0] == etype.RemovedRefList[j].Member.ID[0] {
-				return etype.RemovedRefList[i].Member.ID[1] < etype.RemovedRefList[j].Member.ID[1]
-			}
-			return etype.RemovedRefList[i].Member.ID[0] < etype.RemovedRefList[j].Member.ID[0]
-		})
-	}
-
-	{
-		entities.ClassList = make([]*Class, len(entities.Classes))
-		i := 0
-		for _, eclass := range entities.Classes {
-			entities.ClassList[i] = eclass
-			i++
-
-			eclass.MemberList = make([]*Member, len(eclass.Members))
-			j := 0
-			for _, emember := range eclass.Members {
-				eclass.MemberList[j] = emember
-				j++
-			}
-			sort.Slice(eclass.MemberList, func(i, j int) bool {
-				it := memberTypeOrder[eclass.MemberList[i].Element.GetMemberType()]
-				jt := memberTypeOrder[eclass.MemberList[j].Element.GetMemberType()]
-				if it == jt {
-					return eclass.MemberList[i].ID[1] < eclass.MemberList[j].ID[1]
+		for _, enum := range channel.Root.Enums {
+			eenum := entities.Enums[enum.Name]
+			if eenum == nil {
+				eenum = &Enum{
+					ID:          enum.Name,
+					Element:     enum.Copy().(*rbxapijson.Enum),
+					Items:       map[string]*EnumItem{},
+					Referrers:   map[[2]string]Referrer{},
+					ChannelOnly: true,
 				}
-				return it < jt
-			})
-		}
-		sort.Slice(entities.ClassList, func(i, j int) bool {
-			return entities.ClassList[i].ID < entities.ClassList[j].ID
-		})
-	}
-
-	{
-		entities.EnumList = make([]*Enum, len(entities.Enums))
-		i := 0
-		for _, eenum := range entities.Enums {
-			entities.EnumList[i] = eenum
-			i++
-
-			eenum.ItemList = make([]*EnumItem, len(eenum.Items))
-			j := 0
-			for _, emember := range eenum.Items {
-				eenum.ItemList[j] = emember
-				j++
+				entities.Enums[eenum.ID] = eenum
 			}
-			sort.Slice(eenum.ItemList, func(i, j int) bool {
-				if eenum.ItemList[i].Element.Value == eenum.ItemList[j].Element.Value {
-					return eenum.ItemList[i].Element.Name < eenum.ItemList[j].Element.Name
+			eenum.Channels = append(eenum.Channels, name)
+			for _, item := range enum.Items {
+				id := [2]string{enum.Name, item.Name}
+				eitem := eenum.Items[id[1]]
+				if eitem == nil {
+					eitem = &EnumItem{
+						ID:          id,
+						Element:     item.Copy().(*rbxapijson.EnumItem),
+						Parent:      eenum,
+						ChannelOnly: true,
+					}
+					eenum.Items[id[1]] = eitem
+					entities.EnumItems[id] = eitem
 				}
-				return eenum.ItemList[i].Element.Value < eenum.ItemList[j].Element.Value
-			})
-		}
-		sort.Slice(entities.EnumList, func(i, j int) bool {
-			return entities.EnumList[i].ID < entities.EnumList[j].ID
-		})
-	}
-
-	entities.TypeList = make([]*Type, 0, len(entities.Types))
-loop:
-	for _, etype := range entities.Types {
-		entities.TypeList = append(entities.TypeList, etype)
-		for i, cat := range entities.TypeCats {
-			if cat.Name == etype.Element.Category {
-				entities.TypeCats[i].Types = append(entities.TypeCats[i].Types, etype)
-				continue loop
+				eitem.Channels = append(eitem.Channels, name)
 			}
 		}
-		entities.TypeCats = append(entities.TypeCats, TypeCategory{
-			Name:  etype.Element.Category,
-			Types: []*Type{etype},
-		})
 	}
-	sort.Slice(entities.TypeList, func(i, j int) bool {
-		return entities.TypeList[i].ID < entities.TypeList[j].ID
-	})
-	sort.Slice(entities.TypeCats, func(i, j int) bool {
-		return entities.TypeCats[i].Name < entities.TypeCats[j].Name
-	})
-	for _, cat := range entities.TypeCats {
-		sort.Slice(cat.Types, func(i, j int) bool {
-			return cat.Types[i].ID < cat.Types[j].ID
-		})
-	}
-
-	for id, eclass := range entities.Classes {
-		super := eclass.Element.Superclass
-		if !eclass.Removed {
-			if s := entities.Classes[super]; s == nil || s.Removed {
-				entities.TreeRoots = append(entities.TreeRoots, eclass)
-			}
-		}
-		for class := entities.Classes[super]; class != nil; class = entities.Classes[super] {
-			if !class.Removed {
-				eclass.Superclasses = append(eclass.Superclasses, class)
-			}
-			super = class.Element.Superclass
-		}
-		for _, sub := range entities.Classes {
-			if sub.Element.Superclass == id && !sub.Removed {
-				eclass.Subclasses = append(eclass.Subclasses, sub)
-			}
-		}
-		sort.Slice(eclass.Subclasses, func(i, j int) bool {
-			return eclass.Subclasses[i].ID < eclass.Subclasses[j].ID
-		})
-	}
-	sort.Slice(entities.TreeRoots, func(i, j int) bool {
-		return entities.TreeRoots[i].ID < entities.TreeRoots[j].ID
-	})
-
-	return entities
 }
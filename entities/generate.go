@@ -0,0 +1,617 @@
+package entities
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+	"github.com/robloxapi/rbxapiref/builds"
+)
+
+// patchMark identifies the content of a patch that has been applied to
+// entities. A patch whose mark differs from the mark of the applied patch at
+// the same position has changed since it was applied, such as by being
+// promoted, edited, re-merged, or by having metadata merged into it.
+type patchMark struct {
+	Info builds.Info
+	// Hash is the hash of the encoded patch, as returned by
+	// builds.HashPatch. Nil if the patch could not be encoded, in which case
+	// the mark is not equal to any other.
+	Hash []byte
+}
+
+func markPatch(patch *builds.Patch) patchMark {
+	mark := patchMark{Info: patch.Info}
+	if sum, err := builds.HashPatch(patch); err == nil {
+		mark.Hash = sum[:]
+	}
+	return mark
+}
+
+func (m patchMark) equal(n patchMark) bool {
+	return m.Hash != nil && n.Hash != nil &&
+		m.Info.Equal(n.Info) &&
+		bytes.Equal(m.Hash, n.Hash)
+}
+
+// NewEntities returns an empty set of entities, to which patches can be
+// applied with Update.
+func NewEntities() *Entities {
+	return &Entities{
+		Classes:   make(map[string]*Class),
+		Members:   make(map[[2]string]*Member),
+		Enums:     make(map[string]*Enum),
+		EnumItems: make(map[[2]string]*EnumItem),
+		Types:     make(map[string]*Type),
+		ext:       apiext.Root{},
+	}
+}
+
+// GenerateEntities generates entities from a list of patches.
+func GenerateEntities(patches []builds.Patch) *Entities {
+	entities := NewEntities()
+	entities.Update(patches)
+	return entities
+}
+
+// Applied returns the number of patches that have been applied to the
+// entities.
+func (entities *Entities) Applied() int {
+	return len(entities.applied)
+}
+
+// Update applies each patch in patches that has not yet been applied to the
+// entities, then regenerates the lists, references, timelines, and class
+// hierarchy of every entity. Channels set with SetChannels are kept, while
+// metadata, documents, libraries, and curated replacements are discarded, and
+// must be added again.
+//
+// The patches already applied must be a prefix of patches. Otherwise, Update
+// returns false without modifying the entities, and the entities must instead
+// be generated from scratch.
+func (entities *Entities) Update(patches []builds.Patch) bool {
+	if len(entities.applied) > len(patches) {
+		return false
+	}
+	for i, mark := range entities.applied {
+		if !mark.equal(markPatch(&patches[i])) {
+			return false
+		}
+	}
+	for i := len(entities.applied); i < len(patches); i++ {
+		entities.applyPatch(&patches[i])
+		entities.applied = append(entities.applied, markPatch(&patches[i]))
+	}
+	entities.index()
+	return true
+}
+
+// applyPatch adds each action of a patch to the history of the entities.
+func (entities *Entities) applyPatch(patch *builds.Patch) {
+	for _, action := range patch.Actions {
+		switch {
+		case action.EnumItem != nil:
+			entities.AddEnumItem(&action, patch)
+		case action.Enum != nil:
+			entities.AddEnum(&action, patch)
+		case action.GetMember() != nil:
+			entities.AddMember(&action, patch)
+		case action.Class != nil:
+			entities.AddClass(&action, patch)
+		}
+	}
+	for _, action := range patch.Metadata {
+		entities.AddMetadata(&action, patch)
+	}
+	for _, key := range patch.Extension.Keys() {
+		entities.AddExtension(key, patch.Extension[key], patch)
+	}
+	// Unlike builds.ReplayExtension, removals are not applied, so that
+	// removed elements retain their last known fields.
+	for i := range patch.Actions {
+		action := &patch.Actions[i]
+		for key, fields := range action.Extension {
+			entities.ext[key] = fields.Copy()
+		}
+		if action.IsExtension() {
+			entities.ext.Set(action.ExtensionKey(), action.Field, action.GetNext())
+		}
+	}
+	for key, fields := range patch.Extension {
+		for name, value := range fields {
+			entities.ext.Set(key, name, value)
+		}
+	}
+}
+
+// forEach calls fn for each integer in [0, n), distributing the calls across
+// goroutines. Calls must not depend on each other.
+func forEach(n int, fn func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < n; i += workers {
+				fn(i)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// parallel calls each function in its own goroutine, and waits for them to
+// return.
+func parallel(fns ...func()) {
+	var wg sync.WaitGroup
+	wg.Add(len(fns))
+	for _, fn := range fns {
+		go func(fn func()) {
+			defer wg.Done()
+			fn()
+		}(fn)
+	}
+	wg.Wait()
+}
+
+// index regenerates the state of each entity that is derived from its
+// history. After the lists are built, the timelines, the references, and the
+// class hierarchy are independent of each other, and are built concurrently.
+// Removal records depend on both the timelines and the hierarchy, and
+// anomalies depend on the hierarchy.
+func (entities *Entities) index() {
+	entities.reset()
+	entities.buildChannels()
+	entities.buildLists()
+	parallel(
+		entities.buildTimelines,
+		func() {
+			entities.buildReferences()
+			entities.buildTypeList()
+			entities.sortReferences()
+		},
+		entities.buildHierarchy,
+	)
+	entities.buildRemovals()
+	entities.buildAnomalies()
+}
+
+// reset clears the derived state of each entity, and discards channel-only
+// entities.
+func (entities *Entities) reset() {
+	for id, eclass := range entities.Classes {
+		if eclass.ChannelOnly {
+			delete(entities.Classes, id)
+		}
+	}
+	for id, emember := range entities.Members {
+		if emember.ChannelOnly {
+			delete(entities.Members, id)
+			delete(emember.Parent.Members, id[1])
+		}
+	}
+	for id, eenum := range entities.Enums {
+		if eenum.ChannelOnly {
+			delete(entities.Enums, id)
+		}
+	}
+	for id, eitem := range entities.EnumItems {
+		if eitem.ChannelOnly {
+			delete(entities.EnumItems, id)
+			delete(eitem.Parent.Items, id[1])
+		}
+	}
+	entities.ClassList = nil
+	entities.TreeRoots = nil
+	entities.EnumList = nil
+	entities.Types = make(map[string]*Type)
+	entities.TypeList = nil
+	entities.TypeCats = nil
+	entities.Libraries = nil
+	entities.LibraryList = nil
+	entities.Coverage = 0
+	entities.ChannelList = nil
+	for _, eclass := range entities.Classes {
+		eclass.Channels = nil
+		eclass.Timeline = nil
+		eclass.Extension = nil
+		eclass.Removal = nil
+		eclass.Superclasses = nil
+		eclass.Subclasses = nil
+		eclass.MemberList = nil
+		eclass.Inherited = nil
+		eclass.Anomalies = nil
+		eclass.References = map[rbxapijson.Type]ElementTyper{}
+		eclass.ReferenceList = nil
+		eclass.Referrers = map[[2]string]Referrer{}
+		eclass.ReferrerList = nil
+		eclass.Document = nil
+		eclass.DocStatus = DocStatus{}
+		eclass.Metadata = Metadata{}
+	}
+	for _, emember := range entities.Members {
+		emember.Channels = nil
+		emember.Timeline = nil
+		emember.Extension = nil
+		emember.Removal = nil
+		emember.References = map[rbxapijson.Type]ElementTyper{}
+		emember.ReferenceList = nil
+		emember.Document = nil
+		emember.DocStatus = DocStatus{}
+		emember.Metadata = Metadata{}
+	}
+	for _, eenum := range entities.Enums {
+		eenum.Channels = nil
+		eenum.Timeline = nil
+		eenum.Extension = nil
+		eenum.Removal = nil
+		eenum.ItemList = nil
+		eenum.Anomalies = nil
+		eenum.Referrers = map[[2]string]Referrer{}
+		eenum.ReferrerList = nil
+		eenum.Document = nil
+		eenum.DocStatus = DocStatus{}
+		eenum.Metadata = Metadata{}
+	}
+	for _, eitem := range entities.EnumItems {
+		eitem.Channels = nil
+		eitem.Timeline = nil
+		eitem.Extension = nil
+		eitem.Document = nil
+		eitem.DocStatus = DocStatus{}
+		eitem.Metadata = Metadata{}
+	}
+
+	for key, fields := range entities.ext {
+		switch key.Type {
+		case "Class":
+			if e := entities.Classes[key.Name]; e != nil {
+				e.Extension = fields
+			}
+		case "Member":
+			if e := entities.Members[[2]string{key.Parent, key.Name}]; e != nil {
+				e.Extension = fields
+			}
+		case "Enum":
+			if e := entities.Enums[key.Name]; e != nil {
+				e.Extension = fields
+			}
+		case "EnumItem":
+			if e := entities.EnumItems[[2]string{key.Parent, key.Name}]; e != nil {
+				e.Extension = fields
+			}
+		}
+	}
+}
+
+// buildLists builds the sorted lists of classes, members, enums, and enum
+// items.
+func (entities *Entities) buildLists() {
+	entities.ClassList = make([]*Class, 0, len(entities.Classes))
+	for _, eclass := range entities.Classes {
+		entities.ClassList = append(entities.ClassList, eclass)
+	}
+	sort.Slice(entities.ClassList, func(i, j int) bool {
+		return entities.ClassList[i].ID < entities.ClassList[j].ID
+	})
+	forEach(len(entities.ClassList), func(i int) {
+		eclass := entities.ClassList[i]
+		eclass.MemberList = make([]*Member, 0, len(eclass.Members))
+		for _, emember := range eclass.Members {
+			eclass.MemberList = append(eclass.MemberList, emember)
+		}
+		sort.Slice(eclass.MemberList, func(i, j int) bool {
+			it := memberTypeOrder[eclass.MemberList[i].Element.GetMemberType()]
+			jt := memberTypeOrder[eclass.MemberList[j].Element.GetMemberType()]
+			if it == jt {
+				return eclass.MemberList[i].ID[1] < eclass.MemberList[j].ID[1]
+			}
+			return it < jt
+		})
+	})
+
+	entities.EnumList = make([]*Enum, 0, len(entities.Enums))
+	for _, eenum := range entities.Enums {
+		entities.EnumList = append(entities.EnumList, eenum)
+	}
+	sort.Slice(entities.EnumList, func(i, j int) bool {
+		return entities.EnumList[i].ID < entities.EnumList[j].ID
+	})
+	forEach(len(entities.EnumList), func(i int) {
+		eenum := entities.EnumList[i]
+		eenum.ItemList = make([]*EnumItem, 0, len(eenum.Items))
+		for _, eitem := range eenum.Items {
+			eenum.ItemList = append(eenum.ItemList, eitem)
+		}
+		sort.Slice(eenum.ItemList, func(i, j int) bool {
+			if eenum.ItemList[i].Element.Value == eenum.ItemList[j].Element.Value {
+				return eenum.ItemList[i].Element.Name < eenum.ItemList[j].Element.Name
+			}
+			return eenum.ItemList[i].Element.Value < eenum.ItemList[j].Element.Value
+		})
+	})
+}
+
+// buildTimelines builds the timeline of each entity.
+func (entities *Entities) buildTimelines() {
+	forEach(len(entities.ClassList), func(i int) {
+		eclass := entities.ClassList[i]
+		eclass.Timeline = buildTimeline(eclass.Patches, nil, apiext.ClassKey(eclass.ID), resolveClass)
+		for _, emember := range eclass.MemberList {
+			emember.Timeline = buildTimeline(emember.Patches, eclass.Patches, apiext.MemberKey(emember.ID[0], emember.ID[1]), resolveMember(emember.ID[1]))
+		}
+	})
+	forEach(len(entities.EnumList), func(i int) {
+		eenum := entities.EnumList[i]
+		eenum.Timeline = buildTimeline(eenum.Patches, nil, apiext.EnumKey(eenum.ID), resolveEnum)
+		for _, eitem := range eenum.ItemList {
+			eitem.Timeline = buildTimeline(eitem.Patches, eenum.Patches, apiext.EnumItemKey(eitem.ID[0], eitem.ID[1]), resolveEnumItem(eitem.ID[1]))
+		}
+	})
+}
+
+// referType records that the element of a referrer refers to a type. current
+// indicates whether the type is referred to by the current element, rather
+// than by a past version of the element.
+func (entities *Entities) referType(referrer Referrer, typ rbxapijson.Type, current bool) {
+	var et ElementTyper
+	switch typ.Category {
+	case "Class":
+		if !current {
+			return
+		}
+		if referrer.Member.Removed {
+			return
+		}
+		eclass := entities.Classes[typ.Name]
+		if eclass == nil {
+			return
+		}
+		if _, ok := eclass.Referrers[referrer.Member.ID]; !ok {
+			eclass.Referrers[referrer.Member.ID] = referrer
+			eclass.ReferrerList = append(eclass.ReferrerList, referrer)
+		}
+		et = eclass
+	case "Enum":
+		if !current {
+			return
+		}
+		if referrer.Member.Removed {
+			return
+		}
+		eenum := entities.Enums[typ.Name]
+		if eenum == nil {
+			return
+		}
+		if _, ok := eenum.Referrers[referrer.Member.ID]; !ok {
+			eenum.Referrers[referrer.Member.ID] = referrer
+			eenum.ReferrerList = append(eenum.ReferrerList, referrer)
+		}
+		et = eenum
+	default:
+		etype := entities.Types[typ.Name]
+		if etype == nil {
+			etype = &Type{
+				ID:          typ.Name,
+				Element:     typ,
+				Removed:     true,
+				Referrers:   map[[2]string]Referrer{},
+				RemovedRefs: map[[2]string]Referrer{},
+			}
+			entities.Types[typ.Name] = etype
+		}
+		if !current || referrer.Member.Removed || referrer.Member.Parent.Removed {
+			if _, ok := etype.RemovedRefs[referrer.Member.ID]; !ok {
+				etype.RemovedRefs[referrer.Member.ID] = referrer
+				etype.RemovedRefList = append(etype.RemovedRefList, referrer)
+			}
+		}
+		if !current {
+			return
+		}
+		if referrer.Member.Removed {
+			return
+		}
+		if !referrer.Member.Parent.Removed {
+			etype.Removed = false
+		}
+		if _, ok := etype.Referrers[referrer.Member.ID]; !ok {
+			etype.Referrers[referrer.Member.ID] = referrer
+			etype.ReferrerList = append(etype.ReferrerList, referrer)
+		}
+		et = etype
+	}
+	if referrer.Member.References[typ] == nil {
+		referrer.Member.References[typ] = et
+		referrer.Member.ReferenceList = append(referrer.Member.ReferenceList, et)
+	}
+}
+
+// buildReferences records the types referred to by each member, both
+// currently and in the past. Referrers are recorded on the referred entities,
+// so members are visited one at a time.
+func (entities *Entities) buildReferences() {
+	for _, eclass := range entities.ClassList {
+		for _, entity := range eclass.MemberList {
+			entities.referMember(entity)
+		}
+	}
+}
+
+func (entities *Entities) referMember(entity *Member) {
+	switch element := entity.Element.(type) {
+	case *rbxapijson.Property:
+		entities.referType(Referrer{entity, nil}, element.ValueType, true)
+		for _, p := range entity.Patches {
+			for _, a := range p.Actions {
+				member := a.Property
+				if member == nil {
+					continue
+				}
+				entities.referType(Referrer{entity, nil}, member.ValueType, false)
+			}
+		}
+	case *rbxapijson.Function:
+		entities.referType(Referrer{entity, nil}, element.ReturnType, true)
+		for i, param := range element.Parameters {
+			entities.referType(Referrer{entity, &element.Parameters[i]}, param.Type, true)
+		}
+		for _, p := range entity.Patches {
+			for _, a := range p.Actions {
+				member := a.Function
+				if member == nil {
+					continue
+				}
+				entities.referType(Referrer{entity, nil}, member.ReturnType, false)
+				for i, param := range member.Parameters {
+					entities.referType(Referrer{entity, &member.Parameters[i]}, param.Type, false)
+				}
+			}
+		}
+	case *rbxapijson.Event:
+		for i, param := range element.Parameters {
+			entities.referType(Referrer{entity, &element.Parameters[i]}, param.Type, true)
+		}
+		for _, p := range entity.Patches {
+			for _, a := range p.Actions {
+				member := a.Event
+				if member == nil {
+					continue
+				}
+				for i, param := range member.Parameters {
+					entities.referType(Referrer{entity, &member.Parameters[i]}, param.Type, false)
+				}
+			}
+		}
+	case *rbxapijson.Callback:
+		entities.referType(Referrer{entity, nil}, element.ReturnType, true)
+		for i, param := range element.Parameters {
+			entities.referType(Referrer{entity, &element.Parameters[i]}, param.Type, true)
+		}
+		for _, p := range entity.Patches {
+			for _, a := range p.Actions {
+				member := a.Callback
+				if member == nil {
+					continue
+				}
+				entities.referType(Referrer{entity, nil}, member.ReturnType, false)
+				for i, param := range member.Parameters {
+					entities.referType(Referrer{entity, &member.Parameters[i]}, param.Type, false)
+				}
+			}
+		}
+	}
+}
+
+// buildTypeList builds the sorted list of types, and groups them by
+// category.
+func (entities *Entities) buildTypeList() {
+	entities.TypeList = make([]*Type, 0, len(entities.Types))
+	for _, etype := range entities.Types {
+		entities.TypeList = append(entities.TypeList, etype)
+	}
+	sort.Slice(entities.TypeList, func(i, j int) bool {
+		return entities.TypeList[i].ID < entities.TypeList[j].ID
+	})
+	cats := map[string]int{}
+	for _, etype := range entities.TypeList {
+		i, ok := cats[etype.Element.Category]
+		if !ok {
+			i = len(entities.TypeCats)
+			cats[etype.Element.Category] = i
+			entities.TypeCats = append(entities.TypeCats, TypeCategory{Name: etype.Element.Category})
+		}
+		entities.TypeCats[i].Types = append(entities.TypeCats[i].Types, etype)
+	}
+	sort.Slice(entities.TypeCats, func(i, j int) bool {
+		return entities.TypeCats[i].Name < entities.TypeCats[j].Name
+	})
+}
+
+func lessReference(a, b ElementTyper) bool {
+	at := a.ElementType()
+	bt := b.ElementType()
+	if at.Category == bt.Category {
+		return at.Name < bt.Name
+	}
+	return at.Category < bt.Category
+}
+
+func sortReferrers(list []Referrer) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Member.ID[0] == list[j].Member.ID[0] {
+			return list[i].Member.ID[1] < list[j].Member.ID[1]
+		}
+		return list[i].Member.ID[0] < list[j].Member.ID[0]
+	})
+}
+
+// sortReferences sorts the references and referrers of each entity, and
+// gathers the references of each class from its members. Each entity is
+// sorted independently.
+func (entities *Entities) sortReferences() {
+	forEach(len(entities.ClassList), func(i int) {
+		eclass := entities.ClassList[i]
+		for _, emember := range eclass.MemberList {
+			sort.Slice(emember.ReferenceList, func(i, j int) bool {
+				return lessReference(emember.ReferenceList[i], emember.ReferenceList[j])
+			})
+			for _, et := range emember.ReferenceList {
+				if typ := et.ElementType(); eclass.References[typ] == nil {
+					eclass.References[typ] = et
+					eclass.ReferenceList = append(eclass.ReferenceList, et)
+				}
+			}
+		}
+		sort.Slice(eclass.ReferenceList, func(i, j int) bool {
+			return lessReference(eclass.ReferenceList[i], eclass.ReferenceList[j])
+		})
+		sortReferrers(eclass.ReferrerList)
+	})
+	forEach(len(entities.EnumList), func(i int) {
+		sortReferrers(entities.EnumList[i].ReferrerList)
+	})
+	forEach(len(entities.TypeList), func(i int) {
+		sortReferrers(entities.TypeList[i].ReferrerList)
+		sortReferrers(entities.TypeList[i].RemovedRefList)
+	})
+}
+
+// buildHierarchy sets the tree roots, and the superclasses, subclasses, and
+// inherited members of each class. Subclasses are indexed by superclass in a
+// single pass over the sorted class list, so each list of subclasses is
+// already sorted.
+func (entities *Entities) buildHierarchy() {
+	subclasses := make(map[string][]*Class, len(entities.ClassList))
+	for _, eclass := range entities.ClassList {
+		if !eclass.Removed {
+			super := eclass.Element.Superclass
+			subclasses[super] = append(subclasses[super], eclass)
+		}
+	}
+	for _, eclass := range entities.ClassList {
+		eclass.Subclasses = subclasses[eclass.ID]
+		super := eclass.Element.Superclass
+		if !eclass.Removed {
+			if s := entities.Classes[super]; s == nil || s.Removed {
+				entities.TreeRoots = append(entities.TreeRoots, eclass)
+			}
+		}
+		for class := entities.Classes[super]; class != nil; class = entities.Classes[super] {
+			if !class.Removed {
+				eclass.Superclasses = append(eclass.Superclasses, class)
+			}
+			super = class.Element.Superclass
+		}
+	}
+	forEach(len(entities.ClassList), func(i int) {
+		entities.ClassList[i].resolveInherited()
+	})
+}
@@ -0,0 +1,301 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/builds"
+	"github.com/robloxapi/rbxapiref/fetch"
+)
+
+// Library is a Lua library, such as math or task, or a set of global
+// variables, such as game and typeof. Libraries are not described by the API
+// dump, and are instead read from a curated file with ReadLibraries.
+type Library struct {
+	ID string
+	// Global is whether the members of the library are global variables,
+	// rather than fields of a global table named after the library.
+	Global bool
+	rbxapijson.Tags
+	// History lists the changes made to the library, from oldest to newest.
+	History []LibraryEvent
+	Removed bool
+
+	Members    map[string]*LibraryMember
+	MemberList []*LibraryMember
+
+	Document  Document
+	DocStatus DocStatus
+}
+
+func (e *Library) IsRemoved() bool         { return e.Removed }
+func (e *Library) GetDocument() Document   { return e.Document }
+func (e *Library) GetDocStatus() DocStatus { return e.DocStatus }
+
+// LibraryMember is a function or value within a library.
+type LibraryMember struct {
+	ID [2]string
+	// MemberType is the kind of member, either "Function" or "Property".
+	MemberType string
+	// ValueType is the type of the value of a property.
+	ValueType rbxapijson.Type
+	// Parameters is the list of parameters of a function.
+	Parameters []rbxapijson.Parameter
+	// Returns is the list of types returned by a function.
+	Returns []rbxapijson.Type
+	rbxapijson.Tags
+	// History lists the changes made to the member, from oldest to newest.
+	History []LibraryEvent
+	Removed bool
+
+	Parent *Library
+
+	Document  Document
+	DocStatus DocStatus
+}
+
+func (e *LibraryMember) IsRemoved() bool         { return e.Removed }
+func (e *LibraryMember) GetDocument() Document   { return e.Document }
+func (e *LibraryMember) GetDocStatus() DocStatus { return e.DocStatus }
+
+// Name returns the name by which the member is accessed from Lua, such as
+// "task.wait", or "game" for a member of a global library.
+func (e *LibraryMember) Name() string {
+	if e.Parent != nil && e.Parent.Global {
+		return e.ID[1]
+	}
+	return e.ID[0] + "." + e.ID[1]
+}
+
+// LibraryEvent is a change made to a library or library member.
+type LibraryEvent struct {
+	// Type is the kind of change.
+	Type patch.Type
+	// Version is the version of the client in which the change was made.
+	Version fetch.Version
+	// Description optionally describes the change.
+	Description string
+	// Build is the first build whose version is at least Version. Nil if no
+	// such build has been applied to the entities.
+	Build *builds.Info
+}
+
+// removedBy returns whether the last event of a history is a removal.
+func removedBy(history []LibraryEvent) bool {
+	return len(history) > 0 && history[len(history)-1].Type == patch.Remove
+}
+
+type jLibraryEvent struct {
+	Type        string
+	Version     string
+	Description string
+}
+
+type jLibraryMember struct {
+	MemberType string
+	Name       string
+	ValueType  rbxapijson.Type
+	Parameters []struct {
+		Name    string
+		Type    rbxapijson.Type
+		Default *string
+	}
+	Returns []rbxapijson.Type
+	Tags    rbxapijson.Tags
+	History []jLibraryEvent
+}
+
+type jLibrary struct {
+	Name    string
+	Global  bool
+	Tags    rbxapijson.Tags
+	History []jLibraryEvent
+	Members []jLibraryMember
+}
+
+// parseLibraryVersion parses a version with up to four components, where
+// missing components are zero. For example, "0.463" is the same as
+// "0.463.0.0".
+func parseLibraryVersion(s string) (v fetch.Version, err error) {
+	parts := strings.Split(s, ".")
+	if len(parts) > 4 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	fields := []*int{&v.Major, &v.Minor, &v.Maint, &v.Build}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		*fields[i] = n
+	}
+	return v, nil
+}
+
+func parseLibraryHistory(list []jLibraryEvent) ([]LibraryEvent, error) {
+	history := make([]LibraryEvent, len(list))
+	for i, jevent := range list {
+		event := &history[i]
+		switch jevent.Type {
+		case "Add":
+			event.Type = patch.Add
+		case "Change":
+			event.Type = patch.Change
+		case "Remove":
+			event.Type = patch.Remove
+		default:
+			return nil, fmt.Errorf("event #%d: unknown type %q", i, jevent.Type)
+		}
+		v, err := parseLibraryVersion(jevent.Version)
+		if err != nil {
+			return nil, fmt.Errorf("event #%d: %w", i, err)
+		}
+		if i > 0 && v.Compare(history[i-1].Version) < 0 {
+			return nil, fmt.Errorf("event #%d: version %s precedes previous event", i, v)
+		}
+		event.Version = v
+		event.Description = jevent.Description
+	}
+	return history, nil
+}
+
+// ReadLibraries reads a curated list of libraries. The list is a JSON object
+// with a Libraries array, in which the history of each library and member is
+// a list of events ordered by version. Versions may omit trailing components.
+// For example:
+//
+//	{"Libraries": [{
+//		"Name": "task",
+//		"History": [{"Type": "Add", "Version": "0.463"}],
+//		"Members": [{
+//			"MemberType": "Function",
+//			"Name": "wait",
+//			"Parameters": [{
+//				"Name": "duration",
+//				"Type": {"Category": "Primitive", "Name": "double"},
+//				"Default": "0"
+//			}],
+//			"Returns": [{"Category": "Primitive", "Name": "double"}]
+//		}]
+//	}, {
+//		"Name": "globals",
+//		"Global": true,
+//		"Members": [{
+//			"MemberType": "Property",
+//			"Name": "game",
+//			"ValueType": {"Category": "Class", "Name": "DataModel"}
+//		}]
+//	}]}
+//
+// MemberType is either "Function" or "Property", and the Type of each event
+// is "Add", "Change", or "Remove". An entity whose last event is a removal is
+// removed. Only JSON is accepted; a list written in another format, such as
+// YAML, must be converted first.
+func ReadLibraries(r io.Reader) ([]*Library, error) {
+	var jfile struct{ Libraries []jLibrary }
+	if err := json.NewDecoder(r).Decode(&jfile); err != nil {
+		return nil, fmt.Errorf("decode libraries: %w", err)
+	}
+	libraries := make([]*Library, 0, len(jfile.Libraries))
+	names := map[string]bool{}
+	for _, jlib := range jfile.Libraries {
+		if jlib.Name == "" {
+			return nil, fmt.Errorf("library #%d: missing name", len(libraries))
+		}
+		if names[jlib.Name] {
+			return nil, fmt.Errorf("library %s: duplicate library", jlib.Name)
+		}
+		names[jlib.Name] = true
+		lib := &Library{
+			ID:      jlib.Name,
+			Global:  jlib.Global,
+			Tags:    jlib.Tags,
+			Members: make(map[string]*LibraryMember, len(jlib.Members)),
+		}
+		var err error
+		if lib.History, err = parseLibraryHistory(jlib.History); err != nil {
+			return nil, fmt.Errorf("library %s: %w", lib.ID, err)
+		}
+		lib.Removed = removedBy(lib.History)
+		for _, jmember := range jlib.Members {
+			member := &LibraryMember{
+				ID:         [2]string{lib.ID, jmember.Name},
+				MemberType: jmember.MemberType,
+				Tags:       jmember.Tags,
+				Parent:     lib,
+			}
+			if jmember.Name == "" {
+				return nil, fmt.Errorf("library %s: member #%d: missing name", lib.ID, len(lib.MemberList))
+			}
+			if lib.Members[jmember.Name] != nil {
+				return nil, fmt.Errorf("library member %s: duplicate member", member.Name())
+			}
+			switch jmember.MemberType {
+			case "Function":
+				for _, jparam := range jmember.Parameters {
+					param := rbxapijson.Parameter{Name: jparam.Name, Type: jparam.Type}
+					if jparam.Default != nil {
+						param.HasDefault = true
+						param.Default = *jparam.Default
+					}
+					member.Parameters = append(member.Parameters, param)
+				}
+				member.Returns = jmember.Returns
+			case "Property":
+				member.ValueType = jmember.ValueType
+			default:
+				return nil, fmt.Errorf("library member %s: unknown member type %q", member.Name(), jmember.MemberType)
+			}
+			if member.History, err = parseLibraryHistory(jmember.History); err != nil {
+				return nil, fmt.Errorf("library member %s: %w", member.Name(), err)
+			}
+			member.Removed = removedBy(member.History)
+			lib.Members[member.ID[1]] = member
+			lib.MemberList = append(lib.MemberList, member)
+		}
+		sort.Slice(lib.MemberList, func(i, j int) bool {
+			return lib.MemberList[i].ID[1] < lib.MemberList[j].ID[1]
+		})
+		libraries = append(libraries, lib)
+	}
+	return libraries, nil
+}
+
+// AddLibraries sets the libraries of the entities, and associates each event
+// in the history of each library and member with the first applied build of
+// the event's version. Like channels, the libraries are discarded by Update,
+// and must be added again.
+func (entities *Entities) AddLibraries(libraries []*Library) {
+	entities.Libraries = make(map[string]*Library, len(libraries))
+	entities.LibraryList = make([]*Library, len(libraries))
+	copy(entities.LibraryList, libraries)
+	sort.Slice(entities.LibraryList, func(i, j int) bool {
+		return entities.LibraryList[i].ID < entities.LibraryList[j].ID
+	})
+	resolve := func(history []LibraryEvent) {
+		for i := range history {
+			event := &history[i]
+			event.Build = nil
+			for _, mark := range entities.applied {
+				if mark.Info.Version.Compare(event.Version) >= 0 {
+					info := mark.Info
+					event.Build = &info
+					break
+				}
+			}
+		}
+	}
+	for _, lib := range entities.LibraryList {
+		entities.Libraries[lib.ID] = lib
+		resolve(lib.History)
+		for _, member := range lib.MemberList {
+			resolve(member.History)
+		}
+	}
+}
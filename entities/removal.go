@@ -0,0 +1,585 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+	"github.com/robloxapi/rbxapiref/builds"
+)
+
+// Removal records the history of a removed entity.
+type Removal struct {
+	// FirstSeen is the first build in which the entity existed.
+	FirstSeen builds.Info
+	// LastSeen is the last build in which the entity existed.
+	LastSeen builds.Info
+	// Removed is the build in which the entity was removed. If the entity was
+	// removed more than once, this is the most recent removal.
+	Removed builds.Info
+	// Lifetime is the total time during which the entity existed, excluding
+	// any time during which it was temporarily removed.
+	Lifetime time.Duration
+	// Signature is the final declaration of the element, in the style of a
+	// legacy API dump, followed by its security and tags.
+	Signature string
+	// Replacement is the suggested replacement of the entity. Nil if no
+	// replacement is known.
+	Replacement *Replacement
+}
+
+// LifetimeString returns the lifetime in years and days.
+func (r *Removal) LifetimeString() string {
+	days := int(r.Lifetime.Hours() / 24)
+	years, days := days/365, days%365
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return strconv.Itoa(n) + " " + unit + "s"
+	}
+	switch {
+	case years > 0 && days > 0:
+		return plural(years, "year") + ", " + plural(days, "day")
+	case years > 0:
+		return plural(years, "year")
+	case days > 0:
+		return plural(days, "day")
+	}
+	return "Less than a day"
+}
+
+// ReplacementReason indicates how the replacement of a removed entity was
+// determined.
+type ReplacementReason int
+
+const (
+	// ReplacedByRename indicates that, in the build that removed the entity,
+	// an element of the same kind and with the same fields was added under a
+	// different name. For a member, the element was added to the same class.
+	// For a class or enum, the element has the same members or items.
+	ReplacedByRename ReplacementReason = iota
+	// ReplacedByMove indicates that, in the build that removed the member, a
+	// member of the same name and type was added to a different class.
+	ReplacedByMove
+	// ReplacedByPreferred indicates that the entity was deprecated in favor of
+	// its PreferredDescendantName tag.
+	ReplacedByPreferred
+	// ReplacedByCurated indicates that the replacement was added with
+	// AddReplacements.
+	ReplacedByCurated
+)
+
+func (r ReplacementReason) String() string {
+	switch r {
+	case ReplacedByRename:
+		return "Renamed"
+	case ReplacedByMove:
+		return "Moved"
+	case ReplacedByPreferred:
+		return "Preferred descendant"
+	case ReplacedByCurated:
+		return "Curated"
+	}
+	return ""
+}
+
+// Replacement is the suggested replacement of a removed entity.
+type Replacement struct {
+	// Ref refers to the replacing element.
+	Ref builds.Ref
+	// Reason indicates how the replacement was determined.
+	Reason ReplacementReason
+	// Entity is the *Class, *Member, *Enum, or *EnumItem referred to by Ref.
+	// Nil if Ref does not refer to a known entity.
+	Entity Entity
+}
+
+// Name returns the name of the replacing element, qualified by the name of its
+// parent if it is a member or enum item.
+func (r *Replacement) Name() string {
+	return refName(r.Ref)
+}
+
+// LinkType returns the type of link to Entity, as accepted by
+// settings.Output.FileLink. Returns an empty string if Entity is nil.
+func (r *Replacement) LinkType() string {
+	return linkType(r.Entity)
+}
+
+// refName returns the name of the element referred to by ref, qualified by the
+// name of its parent if it is a member or enum item.
+func refName(ref builds.Ref) string {
+	if ref.Secondary == "" {
+		return ref.Primary
+	}
+	return ref.Primary + "." + ref.Secondary
+}
+
+// linkType returns the type of link to a *Class, *Member, *Enum, or *EnumItem,
+// as accepted by settings.Output.FileLink, or an empty string for any other
+// entity.
+func linkType(entity Entity) string {
+	switch entity.(type) {
+	case *Class:
+		return "class"
+	case *Member:
+		return "member"
+	case *Enum:
+		return "enum"
+	case *EnumItem:
+		return "enumitem"
+	}
+	return ""
+}
+
+// newReplacement returns a replacement that refers to an entity.
+func newReplacement(entity Entity, reason ReplacementReason) *Replacement {
+	return &Replacement{Ref: refOf(entity), Reason: reason, Entity: entity}
+}
+
+// refOf returns a reference to a *Class, *Member, *Enum, or *EnumItem.
+func refOf(entity Entity) builds.Ref {
+	switch e := entity.(type) {
+	case *Class:
+		return builds.Ref{Kind: builds.RefClass, Primary: e.ID}
+	case *Member:
+		return builds.Ref{Kind: builds.RefClass, Primary: e.ID[0], Secondary: e.ID[1]}
+	case *Enum:
+		return builds.Ref{Kind: builds.RefEnum, Primary: e.ID}
+	case *EnumItem:
+		return builds.Ref{Kind: builds.RefEnum, Primary: e.ID[0], Secondary: e.ID[1]}
+	}
+	return builds.Ref{}
+}
+
+// lookupRef returns the entity referred to by ref, or nil if there is no such
+// entity. A reference without a kind prefers classes over enums.
+func (entities *Entities) lookupRef(ref builds.Ref) Entity {
+	if ref.Kind == builds.RefAny || ref.Kind == builds.RefClass {
+		if ref.Secondary == "" {
+			if e := entities.Classes[ref.Primary]; e != nil {
+				return e
+			}
+		} else if e := entities.Members[[2]string{ref.Primary, ref.Secondary}]; e != nil {
+			return e
+		}
+	}
+	if ref.Kind == builds.RefAny || ref.Kind == builds.RefEnum {
+		if ref.Secondary == "" {
+			if e := entities.Enums[ref.Primary]; e != nil {
+				return e
+			}
+		} else if e := entities.EnumItems[[2]string{ref.Primary, ref.Secondary}]; e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// newRemoval returns the removal record of an entity from its timeline, or nil
+// if the entity is not removed. previous maps the hash of each applied build
+// to the build applied before it.
+func newRemoval(t Timeline, previous map[string]builds.Info) *Removal {
+	intervals := t.presence()
+	n := len(intervals)
+	if n == 0 || intervals[n-1].End == nil {
+		return nil
+	}
+	r := &Removal{
+		FirstSeen: intervals[0].Start,
+		LastSeen:  intervals[n-1].Start,
+		Removed:   *intervals[n-1].End,
+	}
+	if prev, ok := previous[r.Removed.Hash]; ok {
+		r.LastSeen = prev
+	}
+	for _, iv := range intervals {
+		r.Lifetime += iv.End.Date.Sub(iv.Start.Date)
+	}
+	return r
+}
+
+// buildRemovals sets the removal record of each removed class, member, and
+// enum, along with any replacement that can be inferred. Timelines and the
+// class hierarchy must already be built.
+func (entities *Entities) buildRemovals() {
+	previous := make(map[string]builds.Info, len(entities.applied))
+	for i := 1; i < len(entities.applied); i++ {
+		previous[entities.applied[i].Info.Hash] = entities.applied[i-1].Info
+	}
+
+	// Entities added in each build, by hash, in the order of the lists. An
+	// interval that continues the previous one was started by a change rather
+	// than an addition.
+	added := map[string][]Entity{}
+	note := func(entity Entity, t Timeline) {
+		intervals := t.presence()
+		for i, iv := range intervals {
+			if i > 0 && intervals[i-1].End.Equal(iv.Start) {
+				continue
+			}
+			added[iv.Start.Hash] = append(added[iv.Start.Hash], entity)
+		}
+	}
+	for _, eclass := range entities.ClassList {
+		note(eclass, eclass.Timeline)
+		for _, emember := range eclass.MemberList {
+			note(emember, emember.Timeline)
+		}
+	}
+	for _, eenum := range entities.EnumList {
+		note(eenum, eenum.Timeline)
+	}
+
+	forEach(len(entities.ClassList), func(i int) {
+		eclass := entities.ClassList[i]
+		if eclass.Removed {
+			if eclass.Removal = newRemoval(eclass.Timeline, previous); eclass.Removal != nil {
+				eclass.Removal.Signature = signature(eclass.Element, "")
+				eclass.Removal.Replacement = entities.inferClass(eclass, added[eclass.Removal.Removed.Hash])
+			}
+		}
+		for _, emember := range eclass.MemberList {
+			if !emember.Removed {
+				continue
+			}
+			if emember.Removal = newRemoval(emember.Timeline, previous); emember.Removal != nil {
+				emember.Removal.Signature = signature(emember.Element, eclass.ID)
+				emember.Removal.Replacement = entities.inferMember(emember, added[emember.Removal.Removed.Hash])
+			}
+		}
+	})
+	forEach(len(entities.EnumList), func(i int) {
+		eenum := entities.EnumList[i]
+		if !eenum.Removed {
+			return
+		}
+		if eenum.Removal = newRemoval(eenum.Timeline, previous); eenum.Removal != nil {
+			eenum.Removal.Signature = signature(eenum.Element, "")
+			eenum.Removal.Replacement = entities.inferEnum(eenum, added[eenum.Removal.Removed.Hash])
+		}
+	})
+}
+
+// preferredName returns the value of the PreferredDescendantName tag of an
+// element.
+func preferredName(ext apiext.Fields) string {
+	name, _ := ext[apiext.TagPrefix+"PreferredDescendantName"].(string)
+	return name
+}
+
+// inferClass infers the replacement of a removed class. added is the list of
+// entities added in the build that removed the class. A class without members
+// is not matched by its shape, since any other empty class would match.
+func (entities *Entities) inferClass(eclass *Class, added []Entity) *Replacement {
+	if name := preferredName(eclass.Extension); name != "" {
+		if e := entities.Classes[name]; e != nil {
+			return newReplacement(e, ReplacedByPreferred)
+		}
+		return &Replacement{Ref: builds.Ref{Kind: builds.RefClass, Primary: name}, Reason: ReplacedByPreferred}
+	}
+	members := classMembers(eclass.Element)
+	if members == "" {
+		return nil
+	}
+	var renames []Entity
+	for _, entity := range added {
+		e, ok := entity.(*Class)
+		if !ok || e == eclass {
+			continue
+		}
+		element, _ := elementAt(eclass.Removal.Removed.Hash, resolveClass, e.Patches).(*rbxapijson.Class)
+		if element != nil &&
+			element.Superclass == eclass.Element.Superclass &&
+			classMembers(element) == members {
+			renames = append(renames, e)
+		}
+	}
+	if len(renames) != 1 {
+		return nil
+	}
+	return newReplacement(renames[0], ReplacedByRename)
+}
+
+// inferMember infers the replacement of a removed member. added is the list of
+// entities added in the build that removed the member. A rename within the
+// class is preferred over a move to another class, and a move that preserves
+// the fields of the member is preferred over one that does not. No replacement
+// is inferred when the preferred kind of candidate is ambiguous.
+func (entities *Entities) inferMember(emember *Member, added []Entity) *Replacement {
+	if name := preferredName(emember.Extension); name != "" {
+		if e := emember.Parent.ResolveMember(name); e != nil {
+			return newReplacement(e, ReplacedByPreferred)
+		}
+		if e := emember.Parent.Members[name]; e != nil {
+			return newReplacement(e, ReplacedByPreferred)
+		}
+		return &Replacement{
+			Ref:    builds.Ref{Kind: builds.RefClass, Primary: emember.ID[0], Secondary: name},
+			Reason: ReplacedByPreferred,
+		}
+	}
+	memberType := emember.Element.GetMemberType()
+	fields := shape(emember.Element)
+	var renames, moves, exactMoves []Entity
+	for _, entity := range added {
+		e, ok := entity.(*Member)
+		if !ok || e == emember {
+			continue
+		}
+		element, _ := elementAt(emember.Removal.Removed.Hash, resolveMember(e.ID[1]), e.Patches, e.Parent.Patches).(rbxapi.Member)
+		if element == nil || element.GetMemberType() != memberType {
+			continue
+		}
+		same := shape(element) == fields
+		switch {
+		case e.Parent == emember.Parent:
+			if same {
+				renames = append(renames, e)
+			}
+		case e.ID[1] == emember.ID[1]:
+			moves = append(moves, e)
+			if same {
+				exactMoves = append(exactMoves, e)
+			}
+		}
+	}
+	switch {
+	case len(renames) == 1:
+		return newReplacement(renames[0], ReplacedByRename)
+	case len(renames) > 1:
+		return nil
+	case len(exactMoves) == 1:
+		return newReplacement(exactMoves[0], ReplacedByMove)
+	case len(exactMoves) == 0 && len(moves) == 1:
+		return newReplacement(moves[0], ReplacedByMove)
+	}
+	return nil
+}
+
+// inferEnum infers the replacement of a removed enum. added is the list of
+// entities added in the build that removed the enum.
+func (entities *Entities) inferEnum(eenum *Enum, added []Entity) *Replacement {
+	if name := preferredName(eenum.Extension); name != "" {
+		if e := entities.Enums[name]; e != nil {
+			return newReplacement(e, ReplacedByPreferred)
+		}
+		return &Replacement{Ref: builds.Ref{Kind: builds.RefEnum, Primary: name}, Reason: ReplacedByPreferred}
+	}
+	items := enumItems(eenum.Element)
+	if items == "" {
+		return nil
+	}
+	var renames []Entity
+	for _, entity := range added {
+		e, ok := entity.(*Enum)
+		if !ok || e == eenum {
+			continue
+		}
+		element, _ := elementAt(eenum.Removal.Removed.Hash, resolveEnum, e.Patches).(*rbxapijson.Enum)
+		if element != nil && enumItems(element) == items {
+			renames = append(renames, e)
+		}
+	}
+	if len(renames) != 1 {
+		return nil
+	}
+	return newReplacement(renames[0], ReplacedByRename)
+}
+
+// elementAt returns the element with which an entity was added in the build
+// of the given hash, as interpreted by resolve from each list of patches.
+// Returns nil if the entity was not added in the build.
+func elementAt(hash string, resolve timelineResolver, patches ...[]builds.Patch) interface{} {
+	for _, list := range patches {
+		for i := range list {
+			p := &list[i]
+			if p.Info.Hash != hash {
+				continue
+			}
+			for j := range p.Actions {
+				if typ, element, ok := resolve(&p.Actions[j]); ok && typ == patch.Add {
+					return element
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// shape returns the fields of an element other than its name and tags, such
+// that two elements of the same kind have the same shape when they differ only
+// by name and tags.
+func shape(element interface{}) string {
+	values := fieldValues(element)
+	fields := make([]string, 0, len(values))
+	for field, value := range values {
+		if field != "Tags" {
+			fields = append(fields, field+"="+value.String())
+		}
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, ";")
+}
+
+// classMembers returns the sorted names of the members of a class, joined
+// into a single string.
+func classMembers(class *rbxapijson.Class) string {
+	names := make([]string, len(class.Members))
+	for i, member := range class.Members {
+		names[i] = member.GetMemberType() + " " + member.GetName()
+	}
+	sort.Strings(names)
+	return strings.Join(names, ";")
+}
+
+// enumItems returns the sorted names and values of the items of an enum,
+// joined into a single string.
+func enumItems(enum *rbxapijson.Enum) string {
+	names := make([]string, len(enum.Items))
+	for i, item := range enum.Items {
+		names[i] = item.Name + "=" + strconv.Itoa(item.Value)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ";")
+}
+
+// signature returns the declaration of an element in the style of a legacy API
+// dump, followed by its security and tags. parent is the name of the class or
+// enum of a member or enum item.
+func signature(element interface{}, parent string) string {
+	var decl string
+	var annotations []string
+	security := func(prefix, s string) {
+		if s != "" && s != "None" {
+			annotations = append(annotations, prefix+s)
+		}
+	}
+	params := func(p []rbxapijson.Parameter) string {
+		list := make([]string, len(p))
+		for i, param := range p {
+			list[i] = param.Type.Name + " " + param.Name
+			if param.HasDefault {
+				list[i] += " = " + param.Default
+			}
+		}
+		return "(" + strings.Join(list, ", ") + ")"
+	}
+	switch e := element.(type) {
+	case *rbxapijson.Class:
+		decl = "Class " + e.Name
+		if e.Superclass != "" && e.Superclass != "<<<ROOT>>>" {
+			decl += " : " + e.Superclass
+		}
+	case *rbxapijson.Property:
+		decl = "Property " + e.ValueType.Name + " " + parent + "." + e.Name
+		if e.ReadSecurity == e.WriteSecurity {
+			security("", e.ReadSecurity)
+		} else {
+			security("ReadSecurity: ", e.ReadSecurity)
+			security("WriteSecurity: ", e.WriteSecurity)
+		}
+	case *rbxapijson.Function:
+		decl = "Function " + e.ReturnType.Name + " " + parent + ":" + e.Name + params(e.Parameters)
+		security("", e.Security)
+	case *rbxapijson.Event:
+		decl = "Event " + parent + "." + e.Name + params(e.Parameters)
+		security("", e.Security)
+	case *rbxapijson.Callback:
+		decl = "Callback " + e.ReturnType.Name + " " + parent + "." + e.Name + params(e.Parameters)
+		security("", e.Security)
+	case *rbxapijson.Enum:
+		decl = "Enum " + e.Name
+	case *rbxapijson.EnumItem:
+		decl = "EnumItem " + parent + "." + e.Name + " : " + strconv.Itoa(e.Value)
+	default:
+		return ""
+	}
+	if t, ok := element.(rbxapi.Taggable); ok {
+		annotations = append(annotations, t.GetTags()...)
+	}
+	for _, a := range annotations {
+		decl += " [" + a + "]"
+	}
+	return decl
+}
+
+// Replacements maps references to removed elements to references to the
+// elements that replace them.
+type Replacements map[builds.Ref]builds.Ref
+
+// ReadReplacements reads a curated list of replacements. The list is a JSON
+// object that maps a reference to each removed element to a reference to its
+// replacement, with each reference parsed by builds.ParseRef. For example:
+//
+//	{
+//		"Instance.remove": "Instance.Destroy",
+//		"class:Hint": "class:Message"
+//	}
+func ReadReplacements(r io.Reader) (Replacements, error) {
+	var list map[string]string
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode replacements: %w", err)
+	}
+	replacements := make(Replacements, len(list))
+	for from, to := range list {
+		fromRef, err := builds.ParseRef(from, "")
+		if err != nil {
+			return nil, fmt.Errorf("replacement of %q: %w", from, err)
+		}
+		toRef, err := builds.ParseRef(to, "")
+		if err != nil {
+			return nil, fmt.Errorf("replacement of %q: %w", from, err)
+		}
+		if fromRef.Kind == builds.RefType || toRef.Kind == builds.RefType {
+			return nil, fmt.Errorf("replacement of %q: type references are not allowed", from)
+		}
+		replacements[fromRef] = toRef
+	}
+	return replacements, nil
+}
+
+// AddReplacements sets curated replacements of removed entities, which take
+// precedence over inferred replacements. Like channels, the replacements are
+// discarded by Update, and must be added again.
+//
+// Each replacement whose reference does not refer to a removed class, member,
+// or enum is skipped, and an error listing such references is returned after
+// the remaining replacements have been added.
+func (entities *Entities) AddReplacements(replacements Replacements) error {
+	var invalid []string
+	for from, to := range replacements {
+		var removal *Removal
+		switch e := entities.lookupRef(from).(type) {
+		case *Class:
+			removal = e.Removal
+		case *Member:
+			removal = e.Removal
+		case *Enum:
+			removal = e.Removal
+		}
+		if removal == nil {
+			invalid = append(invalid, from.String())
+			continue
+		}
+		removal.Replacement = &Replacement{Ref: to, Reason: ReplacedByCurated}
+		if e := entities.lookupRef(to); e != nil {
+			removal.Replacement.Ref = refOf(e)
+			removal.Replacement.Entity = e
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("replacements do not refer to removed elements: %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
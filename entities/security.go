@@ -0,0 +1,221 @@
+package entities
+
+import (
+	"sort"
+
+	"github.com/robloxapi/rbxapi"
+)
+
+// SecurityContexts returns the names of the known security contexts, sorted
+// from least to most privileged. Code running with a context can use elements
+// that require the context or any context before it.
+func SecurityContexts() []string {
+	contexts := make([]string, 0, len(securityContexts))
+	for context := range securityContexts {
+		contexts = append(contexts, context)
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		return securityContexts[contexts[i]] < securityContexts[contexts[j]]
+	})
+	return contexts
+}
+
+// IsSecurityContext returns whether context is a known security context.
+func IsSecurityContext(context string) bool {
+	_, ok := securityContexts[context]
+	return ok
+}
+
+// Permits returns whether code running with the given security context can
+// use an element that requires the given security. An empty security
+// requires None. An unknown security is assumed to be more privileged than
+// every known context, and is permitted only when context is empty, which
+// permits everything.
+func Permits(context, security string) bool {
+	if context == "" {
+		return true
+	}
+	if security == "" {
+		security = "None"
+	}
+	required, ok := securityContexts[security]
+	if !ok {
+		return false
+	}
+	return required <= securityContexts[context]
+}
+
+// Access indicates how much of an element can be used from a security
+// context.
+type Access int
+
+const (
+	// AccessNone indicates that the element cannot be used.
+	AccessNone Access = iota
+	// AccessPartial indicates that only some of the element can be used, such
+	// as a property that can be read but not written, or a class only some of
+	// whose members can be used.
+	AccessPartial
+	// AccessFull indicates that the entire element can be used.
+	AccessFull
+)
+
+func (a Access) String() string {
+	switch a {
+	case AccessNone:
+		return "None"
+	case AccessPartial:
+		return "Partial"
+	case AccessFull:
+		return "Full"
+	}
+	return ""
+}
+
+func memberAccess(member rbxapi.Member, context string) Access {
+	switch m := member.(type) {
+	case interface{ GetSecurity() (string, string) }:
+		r, w := m.GetSecurity()
+		switch pr, pw := Permits(context, r), Permits(context, w); {
+		case pr && pw:
+			return AccessFull
+		case pr || pw:
+			return AccessPartial
+		}
+		return AccessNone
+	case interface{ GetSecurity() string }:
+		if Permits(context, m.GetSecurity()) {
+			return AccessFull
+		}
+		return AccessNone
+	}
+	return AccessFull
+}
+
+// Access returns how much of the member can be used from the given security
+// context.
+func (e *Member) Access(context string) Access {
+	return memberAccess(e.Element, context)
+}
+
+// Access returns how much of the class can be used from the given security
+// context, according to the current members declared by the class. A class
+// without current members is fully accessible.
+func (e *Class) Access(context string) Access {
+	var full, none bool
+	for _, member := range e.MemberList {
+		if member.Removed {
+			continue
+		}
+		switch member.Access(context) {
+		case AccessNone:
+			none = true
+		case AccessPartial:
+			return AccessPartial
+		case AccessFull:
+			full = true
+		}
+		if full && none {
+			return AccessPartial
+		}
+	}
+	if none {
+		return AccessNone
+	}
+	return AccessFull
+}
+
+// Visible returns whether a member is displayed when the API is viewed from
+// the SecurityContext of the entities. A member is hidden when none of it can
+// be used from the context.
+func (entities *Entities) Visible(member *Member) bool {
+	return member.Access(entities.SecurityContext) != AccessNone
+}
+
+// VisibleMembers returns the members of a list that are displayed when the API
+// is viewed from the SecurityContext of the entities. The list is returned as
+// is if there is no context.
+func (entities *Entities) VisibleMembers(list []*Member) []*Member {
+	if entities.SecurityContext == "" {
+		return list
+	}
+	visible := make([]*Member, 0, len(list))
+	for _, member := range list {
+		if entities.Visible(member) {
+			visible = append(visible, member)
+		}
+	}
+	return visible
+}
+
+// SecurityStats summarizes the current API as viewed from a security context.
+type SecurityStats struct {
+	// Context is the security context.
+	Context string
+	// Classes is the number of current classes with at least some access.
+	Classes int
+	// Members maps each member type to the number of current members of the
+	// type with at least some access.
+	Members map[string]int
+	// Total is the number of current members with at least some access.
+	Total int
+	// Partial is the number of current members with partial access.
+	Partial int
+	// Hidden is the number of current members with no access.
+	Hidden int
+	// Required is the number of current members that require exactly the
+	// context. For properties, the read security is used.
+	Required int
+}
+
+// SecurityStats returns statistics of the current API for each security
+// context, from least to most privileged. Members of removed classes are
+// excluded.
+func (entities *Entities) SecurityStats() []SecurityStats {
+	contexts := SecurityContexts()
+	stats := make([]SecurityStats, len(contexts))
+	for i, context := range contexts {
+		stats[i] = SecurityStats{Context: context, Members: map[string]int{}}
+	}
+	for _, class := range entities.ClassList {
+		if class.Removed {
+			continue
+		}
+		for i := range stats {
+			if class.Access(stats[i].Context) != AccessNone {
+				stats[i].Classes++
+			}
+		}
+		for _, member := range class.MemberList {
+			if member.Removed {
+				continue
+			}
+			var security string
+			switch m := member.Element.(type) {
+			case interface{ GetSecurity() (string, string) }:
+				security, _ = m.GetSecurity()
+			case interface{ GetSecurity() string }:
+				security = m.GetSecurity()
+			}
+			if security == "" {
+				security = "None"
+			}
+			for i := range stats {
+				s := &stats[i]
+				if security == s.Context {
+					s.Required++
+				}
+				switch member.Access(s.Context) {
+				case AccessNone:
+					s.Hidden++
+					continue
+				case AccessPartial:
+					s.Partial++
+				}
+				s.Members[member.Element.GetMemberType()]++
+				s.Total++
+			}
+		}
+	}
+	return stats
+}
@@ -0,0 +1,388 @@
+package entities
+
+import (
+	"sort"
+
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+	"github.com/robloxapi/rbxapiref/builds"
+)
+
+// Interval is a span of builds during which a field of an entity held a single
+// value.
+type Interval struct {
+	// Value is the value held by the field.
+	Value *builds.Value
+	// Start is the build in which the field took on the value.
+	Start builds.Info
+	// End is the build in which the value was replaced, or in which the entity
+	// was removed. Nil if the value is current.
+	End *builds.Info
+}
+
+// Contains returns whether the value was held in the given build.
+func (iv Interval) Contains(info builds.Info) bool {
+	if info.Date.Before(iv.Start.Date) {
+		return false
+	}
+	return iv.End == nil || info.Date.Before(iv.End.Date)
+}
+
+// Timeline maps the name of each field of an entity to the values held by the
+// field over time. The intervals of a field are ordered by build, and do not
+// overlap. A gap between intervals indicates that the entity did not exist, or,
+// for an extended field, that the field was not reported.
+type Timeline map[string][]Interval
+
+// Fields returns the names of the fields in the timeline, sorted.
+func (t Timeline) Fields() []string {
+	fields := make([]string, 0, len(t))
+	for field := range t {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// At returns the value of a field in the given build. Returns nil if the
+// entity did not exist in the build.
+func (t Timeline) At(field string, info builds.Info) *builds.Value {
+	for _, iv := range t[field] {
+		if iv.Contains(info) {
+			return iv.Value
+		}
+	}
+	return nil
+}
+
+// Snapshot returns the value of each field in the given build. Fields whose
+// entity did not exist in the build are excluded.
+func (t Timeline) Snapshot(info builds.Info) map[string]*builds.Value {
+	values := map[string]*builds.Value{}
+	for field := range t {
+		if v := t.At(field, info); v != nil {
+			values[field] = v
+		}
+	}
+	return values
+}
+
+// Current returns the interval of the current value of a field. Returns nil if
+// the entity is removed.
+func (t Timeline) Current(field string) *Interval {
+	intervals := t[field]
+	if len(intervals) == 0 {
+		return nil
+	}
+	if iv := &intervals[len(intervals)-1]; iv.End == nil {
+		return iv
+	}
+	return nil
+}
+
+// Since returns the build in which a field took on its current value. Returns
+// nil if the entity is removed.
+func (t Timeline) Since(field string) *builds.Info {
+	if iv := t.Current(field); iv != nil {
+		info := iv.Start
+		return &info
+	}
+	return nil
+}
+
+// presence returns the intervals during which the entity existed.
+func (t Timeline) presence() []Interval {
+	// Every field other than an extended field starts and ends with the
+	// entity, so any such field will do.
+	for _, field := range t.Fields() {
+		if !apiext.IsField(field) {
+			return t[field]
+		}
+	}
+	return nil
+}
+
+// Added returns the build in which the entity was most recently added. Returns
+// nil if the entity is removed.
+func (t Timeline) Added() *builds.Info {
+	intervals := t.presence()
+	i := len(intervals) - 1
+	if i < 0 || intervals[i].End != nil {
+		return nil
+	}
+	for ; i > 0; i-- {
+		if prev := intervals[i-1].End; prev == nil || !prev.Equal(intervals[i].Start) {
+			break
+		}
+	}
+	info := intervals[i].Start
+	return &info
+}
+
+// Changes returns the builds in which the value of a field was changed,
+// excluding builds in which the entity was added or removed.
+func (t Timeline) Changes(field string) []builds.Info {
+	var infos []builds.Info
+	intervals := t[field]
+	for i := 1; i < len(intervals); i++ {
+		if prev := intervals[i-1].End; prev != nil && prev.Equal(intervals[i].Start) {
+			infos = append(infos, intervals[i].Start)
+		}
+	}
+	return infos
+}
+
+// Changed returns whether the value of a field changed at least once while
+// the entity existed.
+func (t Timeline) Changed(field string) bool {
+	return len(t.Changes(field)) > 0
+}
+
+// start begins an interval for each field in values. Current intervals are
+// ended first.
+func (t Timeline) start(values map[string]*builds.Value, info builds.Info) {
+	t.end(info)
+	for field, value := range values {
+		t[field] = append(t[field], Interval{Value: value, Start: info})
+	}
+}
+
+// end ends the current interval of each field.
+func (t Timeline) end(info builds.Info) {
+	for _, intervals := range t {
+		if n := len(intervals); n > 0 && intervals[n-1].End == nil {
+			end := info
+			intervals[n-1].End = &end
+		}
+	}
+}
+
+// startField begins an interval for a single field. Does nothing if the field
+// already has a current value, or if the entity does not exist.
+func (t Timeline) startField(field string, value *builds.Value, info builds.Info) {
+	if t.Current(field) != nil {
+		return
+	}
+	for _, intervals := range t {
+		if n := len(intervals); n > 0 && intervals[n-1].End == nil {
+			t[field] = append(t[field], Interval{Value: value, Start: info})
+			return
+		}
+	}
+}
+
+// endField ends the current interval of a single field.
+func (t Timeline) endField(field string, info builds.Info) {
+	if iv := t.Current(field); iv != nil {
+		end := info
+		iv.End = &end
+	}
+}
+
+// change replaces the current value of a field. Does nothing if the value is
+// unchanged, or if the field has no current value. An extended field instead
+// ends when value is nil, and begins when it has no current value.
+func (t Timeline) change(field string, value *builds.Value, info builds.Info) {
+	iv := t.Current(field)
+	if apiext.IsField(field) {
+		switch {
+		case value == nil:
+			t.endField(field, info)
+			return
+		case iv == nil:
+			t.startField(field, value, info)
+			return
+		}
+	}
+	if iv == nil || value == nil || iv.Value.String() == value.String() {
+		return
+	}
+	end := info
+	iv.End = &end
+	t[field] = append(t[field], Interval{Value: value, Start: info})
+}
+
+// fieldValues returns the value of each field of an element that is tracked by
+// a timeline.
+func fieldValues(element interface{}) map[string]*builds.Value {
+	values := map[string]*builds.Value{}
+	set := func(field string, value interface{}) {
+		values[field] = builds.WrapValue(value)
+	}
+	switch e := element.(type) {
+	case *rbxapijson.Class:
+		set("Superclass", e.Superclass)
+		set("MemoryCategory", e.MemoryCategory)
+		set("Tags", []string(e.GetTags()))
+	case *rbxapijson.Property:
+		set("ValueType", e.ValueType)
+		set("Category", e.Category)
+		set("ReadSecurity", e.ReadSecurity)
+		set("WriteSecurity", e.WriteSecurity)
+		set("CanLoad", e.CanLoad)
+		set("CanSave", e.CanSave)
+		set("Tags", []string(e.GetTags()))
+	case *rbxapijson.Function:
+		set("Parameters", copyParameters(e.Parameters))
+		set("ReturnType", e.ReturnType)
+		set("Security", e.Security)
+		set("Tags", []string(e.GetTags()))
+	case *rbxapijson.Event:
+		set("Parameters", copyParameters(e.Parameters))
+		set("Security", e.Security)
+		set("Tags", []string(e.GetTags()))
+	case *rbxapijson.Callback:
+		set("Parameters", copyParameters(e.Parameters))
+		set("ReturnType", e.ReturnType)
+		set("Security", e.Security)
+		set("Tags", []string(e.GetTags()))
+	case *rbxapijson.Enum:
+		set("Tags", []string(e.GetTags()))
+	case *rbxapijson.EnumItem:
+		set("Value", e.Value)
+		set("Tags", []string(e.GetTags()))
+	}
+	return values
+}
+
+// copyParameters returns a copy of a list of parameters, so that the value
+// does not share memory with the element.
+func copyParameters(params []rbxapijson.Parameter) rbxapijson.Parameters {
+	list := make([]rbxapijson.Parameter, len(params))
+	copy(list, params)
+	return rbxapijson.Parameters{List: &list}
+}
+
+// timelineResolver interprets an action in the history of an entity. It
+// returns the type of the action as it applies to the entity, and, for Add,
+// the element of the entity. ok is false if the action does not apply to the
+// entity.
+type timelineResolver func(action *builds.Action) (typ patch.Type, element interface{}, ok bool)
+
+// buildTimeline computes the timeline of an entity from its patches. For a
+// member or enum item, parent contains the patches of its class or enum, which
+// add and remove the entity along with the parent. Patches of parent whose
+// build also appears in patches are skipped, since the relevant action of the
+// parent has already been copied to patches.
+//
+// key identifies the element of the entity, and selects its extended fields.
+// An extended field that became known after the entity was added begins in
+// the build in which it became known.
+func buildTimeline(patches, parent []builds.Patch, key apiext.Key, resolve timelineResolver) Timeline {
+	t := Timeline{}
+	apply := func(p *builds.Patch) {
+		for i := range p.Actions {
+			action := &p.Actions[i]
+			typ, element, ok := resolve(action)
+			if !ok {
+				continue
+			}
+			switch typ {
+			case patch.Add:
+				values := fieldValues(element)
+				for name, value := range action.Extension[key] {
+					values[name] = builds.WrapValue(value)
+				}
+				t.start(values, p.Info)
+			case patch.Remove:
+				t.end(p.Info)
+			case patch.Change:
+				t.change(action.Field, action.Next, p.Info)
+			}
+		}
+		for name, value := range p.Extension[key] {
+			if value == nil {
+				t.endField(name, p.Info)
+			} else {
+				t.startField(name, builds.WrapValue(value), p.Info)
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(patches) || j < len(parent) {
+		switch {
+		case j >= len(parent):
+			apply(&patches[i])
+			i++
+		case i >= len(patches) || parent[j].Info.Date.Before(patches[i].Info.Date):
+			apply(&parent[j])
+			j++
+		default:
+			if parent[j].Info.Equal(patches[i].Info) {
+				j++
+			}
+			apply(&patches[i])
+			i++
+		}
+	}
+	return t
+}
+
+func resolveClass(action *builds.Action) (patch.Type, interface{}, bool) {
+	if action.Class == nil || action.GetMember() != nil {
+		return 0, nil, false
+	}
+	return action.Type, action.Class, true
+}
+
+// resolveMember returns a resolver for the member of the given name. The
+// patches of a member may include actions on its class that add or remove the
+// member along with the class.
+func resolveMember(name string) timelineResolver {
+	return func(action *builds.Action) (patch.Type, interface{}, bool) {
+		if member := action.GetMember(); member != nil {
+			if member.GetName() != name {
+				return 0, nil, false
+			}
+			return action.Type, member, true
+		}
+		if action.Class == nil {
+			return 0, nil, false
+		}
+		switch action.Type {
+		case patch.Add:
+			if member := action.Class.GetMember(name); member != nil {
+				return patch.Add, member, true
+			}
+			return patch.Remove, nil, true
+		case patch.Remove:
+			return patch.Remove, nil, true
+		}
+		return 0, nil, false
+	}
+}
+
+func resolveEnum(action *builds.Action) (patch.Type, interface{}, bool) {
+	if action.Enum == nil || action.EnumItem != nil {
+		return 0, nil, false
+	}
+	return action.Type, action.Enum, true
+}
+
+// resolveEnumItem returns a resolver for the enum item of the given name. The
+// patches of an item may include actions on its enum that add or remove the
+// item along with the enum.
+func resolveEnumItem(name string) timelineResolver {
+	return func(action *builds.Action) (patch.Type, interface{}, bool) {
+		if item := action.EnumItem; item != nil {
+			if item.Name != name {
+				return 0, nil, false
+			}
+			return action.Type, item, true
+		}
+		if action.Enum == nil {
+			return 0, nil, false
+		}
+		switch action.Type {
+		case patch.Add:
+			if item := action.Enum.GetEnumItem(name); item != nil {
+				return patch.Add, item, true
+			}
+			return patch.Remove, nil, true
+		case patch.Remove:
+			return patch.Remove, nil, true
+		}
+		return 0, nil, false
+	}
+}
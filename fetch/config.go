@@ -33,6 +33,7 @@ import (
 
 	"github.com/robloxapi/rbxapi"
 	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
 	"github.com/robloxapi/rbxdhist"
 	"github.com/robloxapi/rbxfile"
 	"github.com/robloxapi/rbxfile/xml"
@@ -643,27 +644,42 @@ func (client *Client) Builds() (builds []Build, err error) {
 // readable:
 //
 //     - .json: An API dump in JSON format.
-func (client *Client) APIDump(hash string) (root *rbxapijson.Root, err error) {
-	try := func(loc Location) (root *rbxapijson.Root, err error) {
+//
+// Fields not represented by rbxapijson are returned as ext.
+func (client *Client) APIDump(hash string) (root *rbxapijson.Root, ext apiext.Root, err error) {
+	root, ext, _, err = client.APIDumpLocation(hash)
+	return root, ext, err
+}
+
+// APIDumpLocation is like APIDump, but also returns the location from which the
+// API dump was retrieved, with the hash expanded.
+func (client *Client) APIDumpLocation(hash string) (root *rbxapijson.Root, ext apiext.Root, loc Location, err error) {
+	try := func(loc Location) (root *rbxapijson.Root, ext apiext.Root, err error) {
 		format, resp, err := client.Get(loc, hash)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		defer resp.Close()
 
 		switch format {
 		case ".json":
-			return rbxapijson.Decode(resp)
+			return apiext.Decode(resp)
 		}
-		return nil, errUnsupportedFormat(format)
+		return nil, nil, errUnsupportedFormat(format)
 	}
 	locs := client.Config.APIDump
-	for i, loc := range locs {
-		if root, err = try(loc); err == nil || i == len(locs)-1 {
+	for i := range locs {
+		loc = locs[i]
+		if root, ext, err = try(loc); err == nil || i == len(locs)-1 {
 			break
 		}
 	}
-	return root, err
+	if err == nil {
+		if u, err := expandHash(loc.URL, hash); err == nil {
+			loc.URL = u
+		}
+	}
+	return root, ext, loc, err
 }
 
 // ReflectionMetadata returns the reflection metadata for the given hash. The
@@ -7,6 +7,7 @@ require (
 	github.com/anaminus/but v0.2.0
 	github.com/gomarkdown/markdown v0.0.0-20190912180731-281270bc6d83
 	github.com/jessevdk/go-flags v1.4.0
+	github.com/klauspost/compress v1.12.3
 	github.com/robloxapi/rbxapi v0.1.0
 	github.com/robloxapi/rbxdhist v0.3.0
 	github.com/robloxapi/rbxfile v0.1.2
@@ -0,0 +1,241 @@
+// The graph package exports the class hierarchy and the type references of
+// entities as a directed graph, which can be written as Graphviz DOT, GraphML,
+// or JSON adjacency lists.
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/robloxapi/rbxapiref/entities"
+)
+
+// Kinds of nodes.
+const (
+	NodeClass = "Class"
+	NodeEnum  = "Enum"
+	NodeType  = "Type"
+)
+
+// Kinds of edges.
+const (
+	// EdgeInherits connects a class to its superclass.
+	EdgeInherits = "Inherits"
+	// EdgeReferences connects a class to a class, enum, or type that is
+	// referred to by the members of the class.
+	EdgeReferences = "References"
+)
+
+// RefKinds lists the kinds of reference that can be selected by
+// Options.RefKinds. Each is the category of a referred type, in lowercase.
+var RefKinds = []string{"class", "enum", "datatype", "primitive", "group"}
+
+// Node is an entity within a graph.
+type Node struct {
+	// ID uniquely identifies the node, in the form "kind:Name", where kind is
+	// "class", "enum", or "type".
+	ID string
+	// Kind is the kind of entity: NodeClass, NodeEnum, or NodeType.
+	Kind string
+	// Name is the name of the entity.
+	Name string
+	// Category is the category of a type. Empty for other kinds.
+	Category string
+	// Removed is whether the entity is removed.
+	Removed bool
+}
+
+// Edge is a directed relationship between two nodes.
+type Edge struct {
+	// From and To are the IDs of the connected nodes.
+	From, To string
+	// Kind is the kind of relationship: EdgeInherits or EdgeReferences.
+	Kind string
+	// Members lists the names of the members of From that refer to To,
+	// sorted. Empty for EdgeInherits.
+	Members []string
+}
+
+// Graph is a directed graph of entities.
+type Graph struct {
+	// Nodes lists each node. Classes are ordered as they are visited from
+	// each root, depth first, and are followed by the remaining referred
+	// nodes, ordered by ID.
+	Nodes []Node
+	// Edges lists each edge, ordered by source node as in Nodes, then by
+	// kind, then by target ID.
+	Edges []Edge
+}
+
+// Options selects the entities and relationships included in a graph.
+type Options struct {
+	// Roots lists the names of the classes from which the class hierarchy is
+	// traversed. If empty, every class without a superclass is a root.
+	Roots []string
+	// Depth is the maximum number of levels of subclasses below each root
+	// that are included. Zero or less includes every level.
+	Depth int
+	// Removed is whether removed classes and referred entities are included.
+	// References are taken from the References of each member, which are
+	// recorded only for current members, so removed members never contribute
+	// edges.
+	Removed bool
+	// RefKinds lists the kinds of reference, from RefKinds, that are included
+	// as edges. If nil, every kind is included. If empty but not nil, no
+	// references are included.
+	RefKinds []string
+}
+
+// classID returns the ID of the node of a class.
+func classID(name string) string { return "class:" + name }
+
+// Build builds a graph of the class hierarchy from ents, along with the
+// references made by the members of the included classes, as recorded in the
+// References of each member. The superclass of a root is not included, but any
+// class, enum, or type referred to by an included class is included regardless
+// of the hierarchy.
+func Build(ents *entities.Entities, opts Options) (*Graph, error) {
+	refKinds := map[string]bool{}
+	if opts.RefKinds == nil {
+		for _, kind := range RefKinds {
+			refKinds[kind] = true
+		}
+	}
+	for _, kind := range opts.RefKinds {
+		kind = strings.ToLower(kind)
+		if !isRefKind(kind) {
+			return nil, fmt.Errorf("unknown reference kind %q", kind)
+		}
+		refKinds[kind] = true
+	}
+
+	subclasses := map[string][]*entities.Class{}
+	for _, eclass := range ents.ClassList {
+		if opts.Removed || !eclass.Removed {
+			super := eclass.Element.Superclass
+			subclasses[super] = append(subclasses[super], eclass)
+		}
+	}
+	var roots []*entities.Class
+	if len(opts.Roots) == 0 {
+		for _, eclass := range ents.ClassList {
+			if !opts.Removed && eclass.Removed {
+				continue
+			}
+			if super := ents.Classes[eclass.Element.Superclass]; super == nil || !opts.Removed && super.Removed {
+				roots = append(roots, eclass)
+			}
+		}
+	} else {
+		for _, name := range opts.Roots {
+			eclass := ents.Classes[name]
+			if eclass == nil {
+				return nil, fmt.Errorf("unknown class %q", name)
+			}
+			if !opts.Removed && eclass.Removed {
+				return nil, fmt.Errorf("class %q is removed", name)
+			}
+			roots = append(roots, eclass)
+		}
+	}
+
+	g := &Graph{}
+	included := map[string]bool{}
+	var classes []*entities.Class
+	var visit func(eclass *entities.Class, depth int)
+	visit = func(eclass *entities.Class, depth int) {
+		id := classID(eclass.ID)
+		if included[id] {
+			return
+		}
+		included[id] = true
+		classes = append(classes, eclass)
+		g.Nodes = append(g.Nodes, Node{ID: id, Kind: NodeClass, Name: eclass.ID, Removed: eclass.Removed})
+		if opts.Depth > 0 && depth >= opts.Depth {
+			return
+		}
+		for _, sub := range subclasses[eclass.ID] {
+			visit(sub, depth+1)
+		}
+	}
+	for _, root := range roots {
+		visit(root, 0)
+	}
+
+	referred := map[string]Node{}
+	for _, eclass := range classes {
+		from := classID(eclass.ID)
+		if super := classID(eclass.Element.Superclass); included[super] {
+			g.Edges = append(g.Edges, Edge{From: from, To: super, Kind: EdgeInherits})
+		}
+		if len(refKinds) == 0 {
+			continue
+		}
+		refs := map[string]map[string]bool{}
+		for _, member := range eclass.MemberList {
+			for _, et := range member.ReferenceList {
+				if !refKinds[strings.ToLower(et.ElementType().Category)] {
+					continue
+				}
+				node := entityNode(et)
+				if !opts.Removed && node.Removed {
+					continue
+				}
+				if !included[node.ID] {
+					referred[node.ID] = node
+				}
+				if refs[node.ID] == nil {
+					refs[node.ID] = map[string]bool{}
+				}
+				refs[node.ID][member.ID[1]] = true
+			}
+		}
+		ids := make([]string, 0, len(refs))
+		for id := range refs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			edge := Edge{From: from, To: id, Kind: EdgeReferences}
+			for name := range refs[id] {
+				edge.Members = append(edge.Members, name)
+			}
+			sort.Strings(edge.Members)
+			g.Edges = append(g.Edges, edge)
+		}
+	}
+
+	ids := make([]string, 0, len(referred))
+	for id := range referred {
+		if !included[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		g.Nodes = append(g.Nodes, referred[id])
+	}
+	return g, nil
+}
+
+func isRefKind(kind string) bool {
+	for _, k := range RefKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// entityNode returns the node of an entity referred to by a member.
+func entityNode(et entities.ElementTyper) Node {
+	switch e := et.(type) {
+	case *entities.Class:
+		return Node{ID: classID(e.ID), Kind: NodeClass, Name: e.ID, Removed: e.Removed}
+	case *entities.Enum:
+		return Node{ID: "enum:" + e.ID, Kind: NodeEnum, Name: e.ID, Removed: e.Removed}
+	}
+	typ := et.ElementType()
+	return Node{ID: "type:" + et.Identifier(), Kind: NodeType, Name: et.Identifier(), Category: typ.Category, Removed: et.IsRemoved()}
+}
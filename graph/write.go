@@ -0,0 +1,185 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// dotQuote returns s as a quoted DOT identifier.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Classes are drawn as
+// boxes, enums as ellipses, and types as plain text. Removed nodes are gray.
+// Inheritance is drawn with hollow arrowheads, and references with dashed
+// lines whose tooltips list the referring members.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("digraph API {\n")
+	for _, node := range g.Nodes {
+		attrs := []string{"label=" + dotQuote(node.Name)}
+		switch node.Kind {
+		case NodeClass:
+			attrs = append(attrs, "shape=box")
+		case NodeEnum:
+			attrs = append(attrs, "shape=ellipse")
+		case NodeType:
+			attrs = append(attrs, "shape=plaintext")
+		}
+		if node.Removed {
+			attrs = append(attrs, "color=gray", "fontcolor=gray")
+		}
+		fmt.Fprintf(&buf, "\t%s [%s];\n", dotQuote(node.ID), strings.Join(attrs, ", "))
+	}
+	for _, edge := range g.Edges {
+		var attrs []string
+		switch edge.Kind {
+		case EdgeInherits:
+			attrs = append(attrs, "arrowhead=empty")
+		case EdgeReferences:
+			attrs = append(attrs,
+				"style=dashed",
+				"weight="+strconv.Itoa(len(edge.Members)),
+				"tooltip="+dotQuote(strings.Join(edge.Members, ", ")),
+			)
+		}
+		fmt.Fprintf(&buf, "\t%s -> %s [%s];\n", dotQuote(edge.From), dotQuote(edge.To), strings.Join(attrs, ", "))
+	}
+	buf.WriteString("}\n")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphmlData `xml:"data"`
+}
+
+type graphml struct {
+	XMLName xml.Name     `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphmlNode `xml:"node"`
+		Edges       []graphmlEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// WriteGraphML writes the graph in the GraphML format. Nodes have the
+// attributes "kind", "name", "category", and "removed", and edges have the
+// attributes "kind", "members", and "weight", where members is a
+// comma-separated list, and weight is the number of members.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphml{Keys: []graphmlKey{
+		{ID: "nk", For: "node", Name: "kind", Type: "string"},
+		{ID: "nn", For: "node", Name: "name", Type: "string"},
+		{ID: "nc", For: "node", Name: "category", Type: "string"},
+		{ID: "nr", For: "node", Name: "removed", Type: "boolean"},
+		{ID: "ek", For: "edge", Name: "kind", Type: "string"},
+		{ID: "em", For: "edge", Name: "members", Type: "string"},
+		{ID: "ew", For: "edge", Name: "weight", Type: "int"},
+	}}
+	doc.Graph.ID = "API"
+	doc.Graph.EdgeDefault = "directed"
+	for _, node := range g.Nodes {
+		n := graphmlNode{ID: node.ID, Data: []graphmlData{
+			{Key: "nk", Value: node.Kind},
+			{Key: "nn", Value: node.Name},
+		}}
+		if node.Category != "" {
+			n.Data = append(n.Data, graphmlData{Key: "nc", Value: node.Category})
+		}
+		n.Data = append(n.Data, graphmlData{Key: "nr", Value: strconv.FormatBool(node.Removed)})
+		doc.Graph.Nodes = append(doc.Graph.Nodes, n)
+	}
+	for _, edge := range g.Edges {
+		e := graphmlEdge{Source: edge.From, Target: edge.To, Data: []graphmlData{
+			{Key: "ek", Value: edge.Kind},
+		}}
+		if len(edge.Members) > 0 {
+			e.Data = append(e.Data,
+				graphmlData{Key: "em", Value: strings.Join(edge.Members, ",")},
+				graphmlData{Key: "ew", Value: strconv.Itoa(len(edge.Members))},
+			)
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, e)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "\t")
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("encode graphml: %w", err)
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+type jsonEdge struct {
+	To      string
+	Kind    string
+	Members []string `json:",omitempty"`
+}
+
+type jsonNode struct {
+	ID       string
+	Kind     string
+	Name     string
+	Category string     `json:",omitempty"`
+	Removed  bool       `json:",omitempty"`
+	Edges    []jsonEdge `json:",omitempty"`
+}
+
+// WriteJSON writes the graph as a JSON array of nodes, in the order of Nodes,
+// where each node lists its outgoing edges.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	nodes := make([]jsonNode, len(g.Nodes))
+	index := make(map[string]int, len(g.Nodes))
+	for i, node := range g.Nodes {
+		nodes[i] = jsonNode{
+			ID:       node.ID,
+			Kind:     node.Kind,
+			Name:     node.Name,
+			Category: node.Category,
+			Removed:  node.Removed,
+		}
+		index[node.ID] = i
+	}
+	for _, edge := range g.Edges {
+		i, ok := index[edge.From]
+		if !ok {
+			continue
+		}
+		nodes[i].Edges = append(nodes[i].Edges, jsonEdge{To: edge.To, Kind: edge.Kind, Members: edge.Members})
+	}
+	je := json.NewEncoder(w)
+	je.SetEscapeHTML(false)
+	je.SetIndent("", "\t")
+	return je.Encode(nodes)
+}
@@ -0,0 +1,153 @@
+package binio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestUvarint(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		value uint64
+		err   error
+	}{
+		{name: "zero", input: []byte{0x00}, value: 0},
+		{name: "two bytes", input: []byte{0xac, 0x02}, value: 300},
+		{
+			name:  "max",
+			input: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+			value: math.MaxUint64,
+		},
+		{
+			name:  "overlong last byte",
+			input: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02},
+			err:   ErrOverflow,
+		},
+		{
+			name:  "overlong continuation",
+			input: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00},
+			err:   ErrOverflow,
+		},
+		{name: "empty", input: []byte{}, err: io.EOF},
+		{name: "truncated", input: []byte{0x80}, err: io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		r := NewReader(bytes.NewReader(test.input))
+		var v uint64
+		ok := r.Uvarint(&v)
+		if test.err != nil {
+			if ok || !errors.Is(r.Err, test.err) {
+				t.Errorf("%s: expected error %v, got %v", test.name, test.err, r.Err)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: unexpected error: %v", test.name, r.Err)
+			continue
+		}
+		if v != test.value {
+			t.Errorf("%s: expected %d, got %d", test.name, test.value, v)
+		}
+	}
+
+	r := NewReader(bytes.NewReader([]byte{0x80, 0x02}))
+	var v uint8
+	if r.Uvarint(&v) || !errors.Is(r.Err, ErrOverflow) {
+		t.Errorf("uint8: expected error %v, got %v", ErrOverflow, r.Err)
+	}
+}
+
+func TestVarint(t *testing.T) {
+	for _, value := range []int64{0, 1, -1, -5, 63, -64, 300, math.MinInt64, math.MaxInt64} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		if !w.Varint(value) {
+			t.Errorf("write %d: unexpected error: %v", value, w.Err)
+			continue
+		}
+		r := NewReader(&buf)
+		var v int64
+		if !r.Varint(&v) {
+			t.Errorf("read %d: unexpected error: %v", value, r.Err)
+			continue
+		}
+		if v != value {
+			t.Errorf("expected %d, got %d", value, v)
+		}
+	}
+
+	r := NewReader(bytes.NewReader([]byte{0x09}))
+	var v int64
+	if !r.Varint(&v) || v != -5 {
+		t.Errorf("zig-zag: expected -5, got %d (%v)", v, r.Err)
+	}
+
+	r = NewReader(bytes.NewReader([]byte{0x80, 0x02}))
+	var i8 int8
+	if r.Varint(&i8) || !errors.Is(r.Err, ErrOverflow) {
+		t.Errorf("int8: expected error %v, got %v", ErrOverflow, r.Err)
+	}
+}
+
+func TestString(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	tests := []struct {
+		name      string
+		input     []byte
+		maxLength uint64
+		value     string
+		err       error
+	}{
+		{name: "empty", input: []byte{0x00}, value: ""},
+		{name: "short", input: []byte("\x05hello"), value: "hello"},
+		{name: "long", input: append([]byte{0xac, 0x02}, long...), value: long},
+		{name: "at max length", input: []byte("\x05hello"), maxLength: 5, value: "hello"},
+		{name: "above max length", input: []byte("\x05hello"), maxLength: 4, err: ErrOverflow},
+		{
+			name:      "huge length",
+			input:     []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+			maxLength: 1 << 20,
+			err:       ErrOverflow,
+		},
+		{name: "missing content", input: []byte{0x05}, err: io.ErrUnexpectedEOF},
+		{name: "truncated short", input: []byte("\x05hel"), err: io.ErrUnexpectedEOF},
+		{name: "truncated long", input: append([]byte{0xac, 0x02}, long[:200]...), err: io.ErrUnexpectedEOF},
+		{name: "truncated length", input: []byte{0xac}, err: io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		r := NewReader(bytes.NewReader(test.input))
+		r.MaxLength = test.maxLength
+		var s string
+		ok := r.String(&s)
+		if test.err != nil {
+			if ok || !errors.Is(r.Err, test.err) {
+				t.Errorf("%s: expected error %v, got %v", test.name, test.err, r.Err)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: unexpected error: %v", test.name, r.Err)
+			continue
+		}
+		if s != test.value {
+			t.Errorf("%s: expected %q, got %q", test.name, test.value, s)
+		}
+	}
+}
+
+func TestWriterOverflow(t *testing.T) {
+	w := NewWriter(ioutil.Discard)
+	if w.Uvarint(-1) || !errors.Is(w.Err, ErrOverflow) {
+		t.Errorf("Uvarint: expected error %v, got %v", ErrOverflow, w.Err)
+	}
+	w = NewWriter(ioutil.Discard)
+	if w.ShortString(strings.Repeat("x", 256)) || !errors.Is(w.Err, ErrOverflow) {
+		t.Errorf("ShortString: expected error %v, got %v", ErrOverflow, w.Err)
+	}
+}
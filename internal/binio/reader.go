@@ -2,15 +2,26 @@ package binio
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"math"
+	"strings"
 )
 
+// ErrOverflow is returned when a value cannot be represented by an encoding
+// or type.
+var ErrOverflow = errors.New("value overflows encoding")
+
 // Reader is a wrapper that keeps track of the number of bytes written.
 type Reader struct {
 	r   io.Reader
 	buf []byte
 	n   int64
 	Err error
+	// MaxLength, if greater than zero, is the maximum length of a string read
+	// by String. Longer strings fail with ErrOverflow.
+	MaxLength uint64
 }
 
 func NewReader(r io.Reader) *Reader {
@@ -95,9 +106,161 @@ invalid:
 	panic("invalid type")
 }
 
-// String reads a short string into data. The first byte is read, indicating the
-// length of the string, and then a number of bytes is read equal to the length.
+// Uvarint reads an unsigned integer encoded with a variable number of bytes,
+// as by encoding/binary.PutUvarint. data must be a pointer to an unsigned
+// integer type. Fails with ErrOverflow if the value does not fit in the type.
+func (r *Reader) Uvarint(data interface{}) (ok bool) {
+	var max uint64
+	switch data.(type) {
+	case *uint8:
+		max = math.MaxUint8
+	case *uint16:
+		max = math.MaxUint16
+	case *uint32:
+		max = math.MaxUint32
+	case *uint64, *uint:
+		max = math.MaxUint64
+	default:
+		panic("invalid type")
+	}
+	if r.Err != nil {
+		return false
+	}
+	var v uint64
+	var shift uint
+	for i := 0; ; i++ {
+		if !r.Bytes(r.buf[:1]) {
+			if i > 0 && r.Err == io.EOF {
+				r.Err = io.ErrUnexpectedEOF
+			}
+			return false
+		}
+		b := r.buf[0]
+		if i == binary.MaxVarintLen64-1 && b > 1 {
+			r.Err = ErrOverflow
+			return false
+		}
+		if b < 0x80 {
+			v |= uint64(b) << shift
+			break
+		}
+		v |= uint64(b&0x7f) << shift
+		shift += 7
+	}
+	if v > max {
+		r.Err = ErrOverflow
+		return false
+	}
+	switch data := data.(type) {
+	case *uint8:
+		*data = uint8(v)
+	case *uint16:
+		*data = uint16(v)
+	case *uint32:
+		*data = uint32(v)
+	case *uint64:
+		*data = v
+	case *uint:
+		*data = uint(v)
+	}
+	return true
+}
+
+// Varint reads a signed integer encoded with a variable number of bytes, as
+// by encoding/binary.PutVarint. data must be a pointer to a signed integer
+// type. Fails with ErrOverflow if the value does not fit in the type.
+func (r *Reader) Varint(data interface{}) (ok bool) {
+	var min, max int64
+	switch data.(type) {
+	case *int8:
+		min, max = math.MinInt8, math.MaxInt8
+	case *int16:
+		min, max = math.MinInt16, math.MaxInt16
+	case *int32:
+		min, max = math.MinInt32, math.MaxInt32
+	case *int64:
+		min, max = math.MinInt64, math.MaxInt64
+	case *int:
+		min, max = math.MinInt64, math.MaxInt64
+		if ^uint(0) == math.MaxUint32 {
+			min, max = math.MinInt32, math.MaxInt32
+		}
+	default:
+		panic("invalid type")
+	}
+	var u uint64
+	if !r.Uvarint(&u) {
+		return false
+	}
+	// Undo zig-zag encoding.
+	v := int64(u >> 1)
+	if u&1 != 0 {
+		v = ^v
+	}
+	if v < min || v > max {
+		r.Err = ErrOverflow
+		return false
+	}
+	switch data := data.(type) {
+	case *int8:
+		*data = int8(v)
+	case *int16:
+		*data = int16(v)
+	case *int32:
+		*data = int32(v)
+	case *int64:
+		*data = v
+	case *int:
+		*data = int(v)
+	}
+	return true
+}
+
+// String reads a string into data. The length of the string is read as a
+// uvarint, and then a number of bytes is read equal to the length. Memory for
+// long strings is allocated as bytes are read, so that a corrupt length does
+// not cause a large allocation.
 func (r *Reader) String(data *string) (ok bool) {
+	if r.Err != nil {
+		return false
+	}
+	var length uint64
+	if !r.Uvarint(&length) {
+		return false
+	}
+	if length > math.MaxInt64 || r.MaxLength > 0 && length > r.MaxLength {
+		r.Err = fmt.Errorf("string of length %d: %w", length, ErrOverflow)
+		return false
+	}
+	if length <= uint64(len(r.buf)) {
+		s := r.buf[:int(length)]
+		if !r.Bytes(s) {
+			if r.Err == io.EOF {
+				r.Err = io.ErrUnexpectedEOF
+			}
+			return false
+		}
+		*data = string(s)
+		return true
+	}
+	var s strings.Builder
+	var n int64
+	n, r.Err = io.CopyN(&s, r.r, int64(length))
+	r.n += n
+	if r.Err != nil {
+		if r.Err == io.EOF {
+			r.Err = io.ErrUnexpectedEOF
+		}
+		return false
+	}
+	*data = s.String()
+	return true
+}
+
+// ShortString reads a short string into data. The first byte is read,
+// indicating the length of the string, and then a number of bytes is read
+// equal to the length.
+func (r *Reader) ShortString(data *string) (ok bool) {
 	if r.Err != nil {
 		return false
 	}
@@ -2,6 +2,7 @@ package binio
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 )
 
@@ -14,7 +15,7 @@ type Writer struct {
 }
 
 func NewWriter(w io.Writer) *Writer {
-	return &Writer{w: w, buf: make([]byte, 8)}
+	return &Writer{w: w, buf: make([]byte, binary.MaxVarintLen64)}
 }
 
 // Write implements the io.Writer interface.
@@ -92,14 +93,85 @@ invalid:
 	panic("invalid type")
 }
 
-// String writes data as a short string. The first written byte is the length,
-// followed by the byte content of the string.
+// Uvarint writes data as an unsigned integer encoded with a variable number of
+// bytes, as by encoding/binary.PutUvarint. data must be an unsigned integer
+// type, or an int. Fails with ErrOverflow if data is a negative int.
+func (w *Writer) Uvarint(data interface{}) (ok bool) {
+	var v uint64
+	switch data := data.(type) {
+	case uint8:
+		v = uint64(data)
+	case uint16:
+		v = uint64(data)
+	case uint32:
+		v = uint64(data)
+	case uint64:
+		v = data
+	case uint:
+		v = uint64(data)
+	case int:
+		if data < 0 {
+			w.Err = ErrOverflow
+			return false
+		}
+		v = uint64(data)
+	default:
+		panic("invalid type")
+	}
+	if w.Err != nil {
+		return false
+	}
+	n := binary.PutUvarint(w.buf[:binary.MaxVarintLen64], v)
+	return w.Bytes(w.buf[:n])
+}
+
+// Varint writes data as a signed integer encoded with a variable number of
+// bytes, as by encoding/binary.PutVarint. data must be a signed integer type.
+func (w *Writer) Varint(data interface{}) (ok bool) {
+	var v int64
+	switch data := data.(type) {
+	case int8:
+		v = int64(data)
+	case int16:
+		v = int64(data)
+	case int32:
+		v = int64(data)
+	case int64:
+		v = data
+	case int:
+		v = int64(data)
+	default:
+		panic("invalid type")
+	}
+	if w.Err != nil {
+		return false
+	}
+	n := binary.PutVarint(w.buf[:binary.MaxVarintLen64], v)
+	return w.Bytes(w.buf[:n])
+}
+
+// String writes data as a string. The length is written as a uvarint, followed
+// by the byte content of the string.
 func (w *Writer) String(data string) (ok bool) {
+	if w.Err != nil {
+		return false
+	}
+	if !w.Uvarint(len(data)) {
+		return false
+	}
+	return w.Bytes([]byte(data))
+}
+
+// ShortString writes data as a short string. The first written byte is the
+// length, followed by the byte content of the string. Fails with ErrOverflow
+// if the string is longer than 255 bytes.
+func (w *Writer) ShortString(data string) (ok bool) {
 	if w.Err != nil {
 		return false
 	}
 	if len(data) >= 1<<8 {
-		panic("string too large")
+		w.Err = fmt.Errorf("string of length %d: %w", len(data), ErrOverflow)
+		return false
 	}
 	if !w.Number(uint8(len(data))) {
 		return false
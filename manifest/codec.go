@@ -0,0 +1,331 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/internal/binio"
+)
+
+// Compression indicates how the content of a manifest file is compressed.
+type Compression uint8
+
+const (
+	// CompressNone indicates that the content is not compressed.
+	CompressNone Compression = iota
+	// CompressGzip indicates that the content is compressed with gzip.
+	CompressGzip
+	// CompressZstd indicates that the content is compressed with zstd.
+	CompressZstd
+)
+
+var compressionNames = [...]string{
+	CompressNone: "none",
+	CompressGzip: "gzip",
+	CompressZstd: "zstd",
+}
+
+func (c Compression) String() string {
+	if int(c) < len(compressionNames) {
+		return compressionNames[c]
+	}
+	return fmt.Sprintf("Compression(%d)", uint8(c))
+}
+
+func (c Compression) MarshalText() (text []byte, err error) {
+	if int(c) >= len(compressionNames) {
+		return nil, fmt.Errorf("unknown compression %d", uint8(c))
+	}
+	return []byte(compressionNames[c]), nil
+}
+
+func (c *Compression) UnmarshalText(text []byte) error {
+	for i, name := range compressionNames {
+		if strings.EqualFold(string(text), name) {
+			*c = Compression(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown compression %q", string(text))
+}
+
+// reader decodes the content of a manifest. Starting with format version 1,
+// strings, classes, and enums are stored once in tables, and referred to by
+// index, and strings, lengths, indices, and integer values are encoded as
+// varints.
+type reader struct {
+	*binio.Reader
+	// format is the format version of the content.
+	format int
+	// Location of the content being decoded, for reporting errors.
+	channel string
+	patch   int
+	action  int
+	// indexed is whether the content refers to tables.
+	indexed bool
+	strings []string
+	classes []*rbxapijson.Class
+	enums   []*rbxapijson.Enum
+}
+
+// maxLength is the maximum length of any list or string. Exceeding the limit
+// indicates that the content is corrupt.
+const maxLength = 1 << 24
+
+// capHint returns the initial capacity of a list of the given length. Lists
+// grow as elements are decoded, so that a corrupt length does not cause a
+// large allocation.
+func capHint(length uint32) int {
+	if length > 256 {
+		return 256
+	}
+	return int(length)
+}
+
+func newReader(r *binio.Reader, format int) *reader {
+	r.MaxLength = maxLength
+	return &reader{Reader: r, format: format, patch: -1, action: -1}
+}
+
+// err returns the error that occurred while decoding, annotated with the
+// location of the error.
+func (br *reader) err() error {
+	if br.Err == nil {
+		return nil
+	}
+	err := br.Err
+	if err == io.EOF {
+		// The content never ends where a value is expected.
+		err = io.ErrUnexpectedEOF
+	}
+	return &DecodeError{
+		Channel: br.channel,
+		Patch:   br.patch,
+		Action:  br.action,
+		Offset:  br.BytesRead(),
+		Err:     err,
+	}
+}
+
+// Length reads a length or index.
+func (br *reader) Length(data *uint32) (ok bool) {
+	if br.format < 1 {
+		ok = br.Number(data)
+	} else {
+		ok = br.Uvarint(data)
+	}
+	if ok && *data > maxLength {
+		br.Err = fmt.Errorf("length %d exceeds limit", *data)
+		return false
+	}
+	return ok
+}
+
+// Int reads an integer value. Before format version 1, integers were encoded
+// as 32-bit numbers, so negative values are recovered by sign extension.
+func (br *reader) Int(data *int) (ok bool) {
+	if br.format >= 1 {
+		return br.Varint(data)
+	}
+	var v uint32
+	if ok = br.Number(&v); ok {
+		*data = int(int32(v))
+	}
+	return ok
+}
+
+// rawString reads a string that is not in the string table.
+func (br *reader) rawString(data *string) (ok bool) {
+	if br.format < 1 {
+		return br.ShortString(data)
+	}
+	return br.Reader.String(data)
+}
+
+// String reads a string, either directly or from the string table.
+func (br *reader) String(data *string) (ok bool) {
+	if !br.indexed {
+		return br.rawString(data)
+	}
+	var i uint32
+	if !br.Length(&i) {
+		return false
+	}
+	if int(i) >= len(br.strings) {
+		br.Err = errors.New("string index out of range")
+		return false
+	}
+	*data = br.strings[i]
+	return true
+}
+
+// readTables reads the string, class, and enum tables.
+func (man *Manifest) readTables(br *reader) {
+	br.indexed = true
+	var length uint32
+	br.Length(&length)
+	br.strings = make([]string, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var s string
+		if !br.rawString(&s) {
+			return
+		}
+		br.strings = append(br.strings, s)
+	}
+	br.Length(&length)
+	br.classes = make([]*rbxapijson.Class, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var class *rbxapijson.Class
+		man.readClass(br, &class)
+		if br.Err != nil {
+			return
+		}
+		br.classes = append(br.classes, class)
+	}
+	br.Length(&length)
+	br.enums = make([]*rbxapijson.Enum, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var enum *rbxapijson.Enum
+		man.readEnum(br, &enum)
+		if br.Err != nil {
+			return
+		}
+		br.enums = append(br.enums, enum)
+	}
+}
+
+// readClassRef reads a class that is referred to by an action.
+func (man *Manifest) readClassRef(br *reader, p **rbxapijson.Class) {
+	if !br.indexed {
+		man.readClass(br, p)
+		return
+	}
+	var i uint32
+	if !br.Length(&i) {
+		return
+	}
+	if int(i) >= len(br.classes) {
+		br.Err = errors.New("class index out of range")
+		return
+	}
+	*p = br.classes[i]
+}
+
+// readEnumRef reads an enum that is referred to by an action.
+func (man *Manifest) readEnumRef(br *reader, p **rbxapijson.Enum) {
+	if !br.indexed {
+		man.readEnum(br, p)
+		return
+	}
+	var i uint32
+	if !br.Length(&i) {
+		return
+	}
+	if int(i) >= len(br.enums) {
+		br.Err = errors.New("enum index out of range")
+		return
+	}
+	*p = br.enums[i]
+}
+
+// writer encodes the content of a manifest. Strings are interned, and each
+// distinct class and enum referred to by an action is encoded once.
+type writer struct {
+	*binio.Writer
+	strings    map[string]uint32
+	stringList []string
+	classes    *table
+	enums      *table
+}
+
+// table accumulates the encoded form of distinct values.
+type table struct {
+	index map[string]uint32
+	data  bytes.Buffer
+}
+
+func newWriter(w *binio.Writer) *writer {
+	return &writer{
+		Writer:  w,
+		strings: map[string]uint32{},
+		classes: &table{index: map[string]uint32{}},
+		enums:   &table{index: map[string]uint32{}},
+	}
+}
+
+// String writes the index of a string, adding it to the string table if
+// necessary.
+func (bw *writer) String(data string) (ok bool) {
+	i, ok := bw.strings[data]
+	if !ok {
+		i = uint32(len(bw.stringList))
+		bw.strings[data] = i
+		bw.stringList = append(bw.stringList, data)
+	}
+	return bw.Length(int(i))
+}
+
+// Length writes a length or index.
+func (bw *writer) Length(n int) (ok bool) {
+	return bw.Uvarint(n)
+}
+
+// Int writes an integer value.
+func (bw *writer) Int(n int) (ok bool) {
+	return bw.Varint(n)
+}
+
+// sub returns a writer that writes to buf, and shares the string table of bw.
+func (bw *writer) sub(buf *bytes.Buffer) *writer {
+	sub := *bw
+	sub.Writer = binio.NewWriter(buf)
+	return &sub
+}
+
+// add encodes a value with the given function, and returns the index of the
+// value within the table.
+func (t *table) add(bw *writer, encode func(*writer)) uint32 {
+	var buf bytes.Buffer
+	sub := bw.sub(&buf)
+	encode(sub)
+	// Strings may have been added to the table.
+	bw.stringList = sub.stringList
+	if sub.Err != nil {
+		bw.Err = sub.Err
+		return 0
+	}
+	key := buf.String()
+	i, ok := t.index[key]
+	if !ok {
+		i = uint32(len(t.index))
+		t.index[key] = i
+		t.data.Write(buf.Bytes())
+	}
+	return i
+}
+
+// writeClassRef writes a reference to a class within the class table.
+func (man *Manifest) writeClassRef(bw *writer, class *rbxapijson.Class) {
+	bw.Length(int(bw.classes.add(bw, func(bw *writer) { man.writeClass(bw, class) })))
+}
+
+// writeEnumRef writes a reference to an enum within the enum table.
+func (man *Manifest) writeEnumRef(bw *writer, enum *rbxapijson.Enum) {
+	bw.Length(int(bw.enums.add(bw, func(bw *writer) { man.writeEnum(bw, enum) })))
+}
+
+// writeTables writes the string, class, and enum tables accumulated by src.
+func (man *Manifest) writeTables(bw *binio.Writer, src *writer) {
+	bw.Uvarint(len(src.stringList))
+	for _, s := range src.stringList {
+		bw.String(s)
+	}
+	bw.Uvarint(len(src.classes.index))
+	bw.Bytes(src.classes.data.Bytes())
+	bw.Uvarint(len(src.enums.index))
+	bw.Bytes(src.enums.data.Bytes())
+}
@@ -1,91 +1,533 @@
 package manifest
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"sort"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/robloxapi/rbxapi"
 	"github.com/robloxapi/rbxapi/patch"
 	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
 	"github.com/robloxapi/rbxapiref/builds"
 	"github.com/robloxapi/rbxapiref/internal/binio"
 )
 
+// Magic is the sequence of bytes at the start of a manifest file that
+// identifies it as such. Manifests written before the header was introduced
+// begin directly with the number of patches, and are read as format version 0.
+const Magic = "RBXAPIMF"
+
+// FormatVersion is the version of the format written by Manifest.WriteTo.
+// Manifests of this version or lower can be read.
+const FormatVersion = 1
+
+// Tool identifies the program that writes manifests. It is included in the
+// header of each written manifest.
+var Tool = "rbxapiref"
+
+// VersionError is returned when reading a manifest with a format version
+// newer than FormatVersion.
+type VersionError struct {
+	// Version is the format version of the manifest.
+	Version int
+	// Tool is the program that wrote the manifest.
+	Tool string
+}
+
+func (err *VersionError) Error() string {
+	return fmt.Sprintf("manifest format version %d (written by %q) is newer than supported version %d", err.Version, err.Tool, FormatVersion)
+}
+
+// DecodeError is returned when the content of a manifest is corrupt. The
+// manifest retains each patch that was decoded before the error occurred.
+type DecodeError struct {
+	// Channel is the name of the channel containing the patch, or empty for
+	// the main list of patches.
+	Channel string
+	// Patch is the index of the patch that failed to decode, or -1 if the
+	// error occurred outside of a patch.
+	Patch int
+	// Action is the index of the action within the patch that failed to
+	// decode, or -1 if the error occurred outside of an action.
+	Action int
+	// Offset is the number of bytes of content read before the error
+	// occurred.
+	Offset int64
+	// Err is the underlying error.
+	Err error
+}
+
+func (err *DecodeError) Error() string {
+	var s string
+	if err.Channel != "" {
+		s += fmt.Sprintf("channel %q: ", err.Channel)
+	}
+	if err.Patch >= 0 {
+		s += fmt.Sprintf("patch %d: ", err.Patch)
+	}
+	if err.Action >= 0 {
+		s += fmt.Sprintf("action %d: ", err.Action)
+	}
+	return fmt.Sprintf("decode manifest: %soffset %d: %s", s, err.Offset, err.Err)
+}
+
+func (err *DecodeError) Unwrap() error {
+	return err.Err
+}
+
 type Manifest struct {
+	// Format is the format version of the file from which the manifest was
+	// read. The manifest is always written with FormatVersion.
+	Format int `json:"-"`
+	// Tool is the program that wrote the file from which the manifest was
+	// read. Empty if the file has no header.
+	Tool string `json:"-"`
+	// Compression is the compression of the file from which the manifest was
+	// read, and the compression used when writing.
+	Compression Compression `json:"-"`
+
+	// Settings is the record of the build settings that produced the
+	// manifest. Nil if unknown.
+	Settings *builds.Record `json:",omitempty"`
+	Patches  []builds.Patch
+	// Channels contains the patches of each additional release channel,
+	// sorted by name.
+	Channels []Channel `json:",omitempty"`
+}
+
+// Channel contains the build history of an additional release channel.
+type Channel struct {
+	Name    string
 	Patches []builds.Patch
 }
 
+// Channel returns the channel of the given name, or nil if no such channel
+// exists.
+func (man *Manifest) Channel(name string) *Channel {
+	for i := range man.Channels {
+		if man.Channels[i].Name == name {
+			return &man.Channels[i]
+		}
+	}
+	return nil
+}
+
+// ReadFrom decodes a manifest of any format version up to FormatVersion. If
+// the content is corrupt, a *DecodeError is returned, and the manifest
+// contains each patch decoded before the point of corruption.
 func (man *Manifest) ReadFrom(r io.Reader) (n int64, err error) {
-	br := binio.NewReader(r)
-	var length uint32
-	br.Number(&length)
-	man.Patches = make([]builds.Patch, length)
-	for i, patch := range man.Patches {
-		man.readPatch(br, &patch)
-		man.Patches[i] = patch
+	// Peek at the start of the file to detect a header. A legacy manifest may
+	// be shorter than the magic.
+	buf := bufio.NewReader(r)
+	br := binio.NewReader(buf)
+	br.MaxLength = maxLength
+	man.Format = 0
+	man.Tool = ""
+	man.Compression = CompressNone
+	if magic, _ := buf.Peek(len(Magic)); string(magic) == Magic {
+		br.Bytes(make([]byte, len(Magic)))
+		var version uint16
+		br.Number(&version)
+		man.Format = int(version)
+		br.String(&man.Tool)
 		if br.Err != nil {
+			return br.End()
+		}
+	}
+
+	// Each case decodes a format version into the current structure.
+	switch man.Format {
+	case 0:
+		cr := newReader(br, man.Format)
+		man.readBody(cr)
+		br.Err = cr.err()
+	case 1:
+		var c uint8
+		if !br.Number(&c) {
 			break
 		}
+		man.Compression = Compression(c)
+		var content io.Reader = br
+		// Compressed stream, read to the end to verify its checksum.
+		var zr io.Reader
+		switch man.Compression {
+		case CompressNone:
+		case CompressGzip:
+			if zr, br.Err = gzip.NewReader(br); br.Err != nil {
+				return br.End()
+			}
+			content = zr
+		case CompressZstd:
+			var zd *zstd.Decoder
+			if zd, br.Err = zstd.NewReader(br, zstd.WithDecoderConcurrency(1)); br.Err != nil {
+				return br.End()
+			}
+			defer zd.Close()
+			zr = zd
+			content = zr
+		default:
+			br.Err = fmt.Errorf("unknown compression %d", c)
+			return br.End()
+		}
+		cr := newReader(binio.NewReader(content), man.Format)
+		man.readTables(cr)
+		if cr.Err == nil {
+			man.readBody(cr)
+		}
+		if cr.Err == nil && zr != nil {
+			// Read to the end of the stream to verify the checksum.
+			if _, err := io.Copy(ioutil.Discard, zr); err != nil {
+				cr.Err = err
+			}
+		}
+		// Errors from the underlying reader, such as EOF, are reported
+		// through the content reader.
+		br.Err = cr.err()
+	default:
+		br.Err = &VersionError{Version: man.Format, Tool: man.Tool}
 	}
 	return br.End()
 }
 
+// WriteTo writes the manifest with the current FormatVersion. The content is
+// compressed according to man.Compression.
 func (man *Manifest) WriteTo(w io.Writer) (n int64, err error) {
+	// Encode the body first, accumulating the tables that precede it.
+	var body bytes.Buffer
+	cw := newWriter(binio.NewWriter(&body))
+	man.writeBody(cw)
+	if cw.Err != nil {
+		return 0, cw.Err
+	}
+
 	bw := binio.NewWriter(w)
-	bw.Number(uint32(len(man.Patches)))
-	for _, patch := range man.Patches {
+	bw.Bytes([]byte(Magic))
+	bw.Number(uint16(FormatVersion))
+	bw.String(Tool)
+	bw.Number(uint8(man.Compression))
+	if bw.Err != nil {
+		return bw.End()
+	}
+	switch man.Compression {
+	case CompressNone:
+		man.writeTables(bw, cw)
+		bw.Bytes(body.Bytes())
+	case CompressGzip:
+		zw := gzip.NewWriter(bw)
+		zbw := binio.NewWriter(zw)
+		man.writeTables(zbw, cw)
+		zbw.Bytes(body.Bytes())
+		if zbw.Err != nil {
+			bw.Err = zbw.Err
+		} else if err := zw.Close(); err != nil {
+			bw.Err = err
+		}
+	case CompressZstd:
+		zw, err := zstd.NewWriter(bw, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			bw.Err = err
+			break
+		}
+		zbw := binio.NewWriter(zw)
+		man.writeTables(zbw, cw)
+		zbw.Bytes(body.Bytes())
+		if zbw.Err != nil {
+			bw.Err = zbw.Err
+			zw.Close()
+		} else if err := zw.Close(); err != nil {
+			bw.Err = err
+		}
+	default:
+		bw.Err = fmt.Errorf("unknown compression %d", man.Compression)
+	}
+	return bw.End()
+}
+
+// readBody reads the patches and channels of a manifest. Manifests of format
+// version 0 may end before the channels.
+func (man *Manifest) readBody(br *reader) {
+	man.Settings = nil
+	if br.format >= 1 {
+		man.readRecord(br)
+	}
+	man.Patches = man.readPatches(br)
+	if br.Err != nil {
+		return
+	}
+	var length uint32
+	if !br.Length(&length) {
+		if br.Err == io.EOF && br.format == 0 {
+			// Manifest predates channels.
+			br.Err = nil
+		}
+		return
+	}
+	man.Channels = make([]Channel, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var channel Channel
+		if !br.String(&channel.Name) {
+			return
+		}
+		br.channel = channel.Name
+		channel.Patches = man.readPatches(br)
+		man.Channels = append(man.Channels, channel)
+		if br.Err != nil {
+			return
+		}
+	}
+	br.channel = ""
+}
+
+func (man *Manifest) writeBody(bw *writer) {
+	man.writeRecord(bw)
+	man.writePatches(bw, man.Patches)
+	bw.Length(len(man.Channels))
+	for _, channel := range man.Channels {
+		bw.String(channel.Name)
+		man.writePatches(bw, channel.Patches)
+		if bw.Err != nil {
+			break
+		}
+	}
+}
+
+func (man *Manifest) readPatches(br *reader) []builds.Patch {
+	var length uint32
+	if !br.Length(&length) {
+		return nil
+	}
+	patches := make([]builds.Patch, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		// Only complete patches are retained.
+		br.patch = int(i)
+		var patch builds.Patch
+		man.readPatch(br, &patch)
+		if br.Err != nil {
+			return patches
+		}
+		patches = append(patches, patch)
+	}
+	br.patch = -1
+	return patches
+}
+
+func (man *Manifest) writePatches(bw *writer, patches []builds.Patch) {
+	bw.Length(len(patches))
+	for _, patch := range patches {
 		man.writePatch(bw, &patch)
 		if bw.Err != nil {
 			break
 		}
 	}
-	return bw.End()
 }
 
-func (man *Manifest) readPatch(br *binio.Reader, patch *builds.Patch) {
+func (man *Manifest) readRecord(br *reader) {
+	var b uint8
+	if !br.Number(&b) || !binio.GetBit(uint64(b), 0) {
+		return
+	}
+	record := &builds.Record{Metadata: binio.GetBit(uint64(b), 1)}
+	var length uint32
+	br.Length(&length)
+	record.Configs = make(map[string]builds.ConfigRecord, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var name string
+		var config builds.ConfigRecord
+		br.String(&name)
+		br.String(&config.API)
+		if !br.String(&config.Metadata) {
+			return
+		}
+		record.Configs[name] = config
+	}
+	record.UseConfigs = man.readStrings(br)
+	br.String(&record.Channel)
+	br.Length(&length)
+	record.Channels = make(map[string][]string, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var name string
+		br.String(&name)
+		configs := man.readStrings(br)
+		if br.Err != nil {
+			return
+		}
+		record.Channels[name] = configs
+	}
+	var rewind uint8
+	if !br.Number(&rewind) {
+		return
+	}
+	if record.Rewind = builds.RewindMode(rewind); record.Rewind > builds.RewindPreview {
+		br.Err = fmt.Errorf("invalid rewind mode %d", rewind)
+		return
+	}
+	man.Settings = record
+}
+
+func (man *Manifest) writeRecord(bw *writer) {
+	record := man.Settings
+	var b uint64
+	b = binio.SetBit(b, 0, record != nil)
+	b = binio.SetBit(b, 1, record != nil && record.Metadata)
+	bw.Number(uint8(b))
+	if record == nil {
+		return
+	}
+	names := make([]string, 0, len(record.Configs))
+	for name := range record.Configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bw.Length(len(names))
+	for _, name := range names {
+		bw.String(name)
+		bw.String(record.Configs[name].API)
+		bw.String(record.Configs[name].Metadata)
+	}
+	man.writeStrings(bw, record.UseConfigs)
+	bw.String(record.Channel)
+	names = names[:0]
+	for name := range record.Channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bw.Length(len(names))
+	for _, name := range names {
+		bw.String(name)
+		man.writeStrings(bw, record.Channels[name])
+	}
+	bw.Number(uint8(record.Rewind))
+}
+
+func (man *Manifest) readPatch(br *reader, patch *builds.Patch) {
 	man.readBuildInfo(br, &patch.Info)
 	var b uint8
 	br.Number(&b)
-	if b != 0 {
+	if binio.GetBit(uint64(b), 0) {
 		patch.Prev = &builds.Info{}
 		man.readBuildInfo(br, patch.Prev)
 	}
+	patch.Unreleased = binio.GetBit(uint64(b), 2)
+	if binio.GetBit(uint64(b), 1) {
+		var length uint32
+		br.Length(&length)
+		patch.Aliases = make([]builds.Info, 0, capHint(length))
+		for i := uint32(0); i < length; i++ {
+			var info builds.Info
+			man.readBuildInfo(br, &info)
+			if br.Err != nil {
+				return
+			}
+			patch.Aliases = append(patch.Aliases, info)
+		}
+	}
+	if binio.GetBit(uint64(b), 3) {
+		patch.Source = &builds.Source{}
+		br.String(&patch.Source.Location)
+		var t string
+		br.String(&t)
+		if err := patch.Source.Time.UnmarshalBinary([]byte(t)); err != nil && br.Err == nil {
+			br.Err = err
+			return
+		}
+		br.String(&patch.Source.Fingerprint)
+	}
 	br.String(&patch.Config)
 	var length uint32
-	br.Number(&length)
-	patch.Actions = make([]builds.Action, length)
-	for i, action := range patch.Actions {
+	br.Length(&length)
+	patch.Actions = make([]builds.Action, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		br.action = int(i)
+		var action builds.Action
 		man.readAction(br, &action)
-		patch.Actions[i] = action
 		if br.Err != nil {
 			return
 		}
+		patch.Actions = append(patch.Actions, action)
+	}
+	br.action = -1
+	if patch.HasMetadata = binio.GetBit(uint64(b), 4); patch.HasMetadata {
+		br.Length(&length)
+		patch.Metadata = make([]builds.MetadataAction, 0, capHint(length))
+		for i := uint32(0); i < length; i++ {
+			var action builds.MetadataAction
+			man.readMetadataAction(br, &action)
+			if br.Err != nil {
+				return
+			}
+			patch.Metadata = append(patch.Metadata, action)
+		}
+	}
+	if binio.GetBit(uint64(b), 5) {
+		man.readExtension(br, &patch.Extension)
 	}
 }
 
-func (man *Manifest) writePatch(bw *binio.Writer, patch *builds.Patch) {
+func (man *Manifest) writePatch(bw *writer, patch *builds.Patch) {
 	man.writeBuildInfo(bw, &patch.Info)
+	var b uint64
+	b = binio.SetBit(b, 0, patch.Prev != nil)
+	b = binio.SetBit(b, 1, len(patch.Aliases) > 0)
+	b = binio.SetBit(b, 2, patch.Unreleased)
+	b = binio.SetBit(b, 3, patch.Source != nil)
+	b = binio.SetBit(b, 4, patch.HasMetadata)
+	b = binio.SetBit(b, 5, len(patch.Extension) > 0)
+	bw.Number(uint8(b))
 	if patch.Prev != nil {
-		bw.Number(uint8(1))
 		man.writeBuildInfo(bw, patch.Prev)
-	} else {
-		bw.Number(uint8(0))
+	}
+	if len(patch.Aliases) > 0 {
+		bw.Length(len(patch.Aliases))
+		for i := range patch.Aliases {
+			man.writeBuildInfo(bw, &patch.Aliases[i])
+		}
+	}
+	if patch.Source != nil {
+		bw.String(patch.Source.Location)
+		t, err := patch.Source.Time.MarshalBinary()
+		if err != nil {
+			bw.Err = err
+			return
+		}
+		bw.String(string(t))
+		bw.String(patch.Source.Fingerprint)
 	}
 	bw.String(patch.Config)
-	bw.Number(uint32(len(patch.Actions)))
+	bw.Length(len(patch.Actions))
 	for _, action := range patch.Actions {
 		man.writeAction(bw, &action)
 		if bw.Err != nil {
 			return
 		}
 	}
+	if patch.HasMetadata {
+		bw.Length(len(patch.Metadata))
+		for _, action := range patch.Metadata {
+			man.writeMetadataAction(bw, &action)
+			if bw.Err != nil {
+				return
+			}
+		}
+	}
+	if len(patch.Extension) > 0 {
+		man.writeExtension(bw, patch.Extension)
+	}
 }
 
-func (man *Manifest) readBuildInfo(br *binio.Reader, info *builds.Info) {
+func (man *Manifest) readBuildInfo(br *reader, info *builds.Info) {
 	br.String(&info.Hash)
 	var date string
-	br.String(&date)
+	if !br.String(&date) {
+		return
+	}
 	if err := info.Date.UnmarshalBinary([]byte(date)); err != nil {
 		br.Err = err
 		return
@@ -101,7 +543,7 @@ func (man *Manifest) readBuildInfo(br *binio.Reader, info *builds.Info) {
 	info.Version.Build = int(v)
 }
 
-func (man *Manifest) writeBuildInfo(bw *binio.Writer, info *builds.Info) {
+func (man *Manifest) writeBuildInfo(bw *writer, info *builds.Info) {
 	bw.String(info.Hash)
 	date, err := info.Date.MarshalBinary()
 	if err != nil {
@@ -115,30 +557,36 @@ func (man *Manifest) writeBuildInfo(bw *binio.Writer, info *builds.Info) {
 	bw.Number(uint32(info.Version.Build))
 }
 
-func (man *Manifest) readAction(br *binio.Reader, action *builds.Action) {
+func (man *Manifest) readAction(br *reader, action *builds.Action) {
 	var data uint8
-	br.Number(&data)
+	if !br.Number(&data) {
+		return
+	}
+	if binio.GetBits(uint64(data), 0, 2) > 2 {
+		br.Err = errors.New("invalid action type")
+		return
+	}
 	action.Type = patch.Type(binio.GetBits(uint64(data), 0, 2) - 1)
 	switch binio.GetBits(uint64(data), 2, 5) {
 	case 1:
-		man.readClass(br, &action.Class)
+		man.readClassRef(br, &action.Class)
 		man.readProperty(br, &action.Property)
 	case 2:
-		man.readClass(br, &action.Class)
+		man.readClassRef(br, &action.Class)
 		man.readFunction(br, &action.Function)
 	case 3:
-		man.readClass(br, &action.Class)
+		man.readClassRef(br, &action.Class)
 		man.readEvent(br, &action.Event)
 	case 4:
-		man.readClass(br, &action.Class)
+		man.readClassRef(br, &action.Class)
 		man.readCallback(br, &action.Callback)
 	case 5:
-		man.readClass(br, &action.Class)
+		man.readClassRef(br, &action.Class)
 	case 6:
-		man.readEnum(br, &action.Enum)
+		man.readEnumRef(br, &action.Enum)
 		man.readEnumItem(br, &action.EnumItem)
 	case 7:
-		man.readEnum(br, &action.Enum)
+		man.readEnumRef(br, &action.Enum)
 	default:
 		br.Err = errors.New("invalid action")
 		return
@@ -148,45 +596,49 @@ func (man *Manifest) readAction(br *binio.Reader, action *builds.Action) {
 		man.readValue(br, &action.Prev)
 		man.readValue(br, &action.Next)
 	}
+	if binio.GetBit(uint64(data), 5) {
+		man.readExtension(br, &action.Extension)
+	}
 }
 
-func (man *Manifest) writeAction(bw *binio.Writer, action *builds.Action) {
+func (man *Manifest) writeAction(bw *writer, action *builds.Action) {
 	var data uint64
 	data = binio.SetBits(data, 0, 2, int(action.Type)+1)
+	data = binio.SetBit(data, 5, len(action.Extension) > 0)
 	switch {
 	case action.Property != nil:
 		data = binio.SetBits(data, 2, 5, 1)
 		bw.Number(uint8(data))
-		man.writeClass(bw, action.Class)
+		man.writeClassRef(bw, action.Class)
 		man.writeProperty(bw, action.Property)
 	case action.Function != nil:
 		data = binio.SetBits(data, 2, 5, 2)
 		bw.Number(uint8(data))
-		man.writeClass(bw, action.Class)
+		man.writeClassRef(bw, action.Class)
 		man.writeFunction(bw, action.Function)
 	case action.Event != nil:
 		data = binio.SetBits(data, 2, 5, 3)
 		bw.Number(uint8(data))
-		man.writeClass(bw, action.Class)
+		man.writeClassRef(bw, action.Class)
 		man.writeEvent(bw, action.Event)
 	case action.Callback != nil:
 		data = binio.SetBits(data, 2, 5, 4)
 		bw.Number(uint8(data))
-		man.writeClass(bw, action.Class)
+		man.writeClassRef(bw, action.Class)
 		man.writeCallback(bw, action.Callback)
 	case action.Class != nil:
 		data = binio.SetBits(data, 2, 5, 5)
 		bw.Number(uint8(data))
-		man.writeClass(bw, action.Class)
+		man.writeClassRef(bw, action.Class)
 	case action.EnumItem != nil:
 		data = binio.SetBits(data, 2, 5, 6)
 		bw.Number(uint8(data))
-		man.writeEnum(bw, action.Enum)
+		man.writeEnumRef(bw, action.Enum)
 		man.writeEnumItem(bw, action.EnumItem)
 	case action.Enum != nil:
 		data = binio.SetBits(data, 2, 5, 7)
 		bw.Number(uint8(data))
-		man.writeEnum(bw, action.Enum)
+		man.writeEnumRef(bw, action.Enum)
 	default:
 		bw.Err = errors.New("invalid action")
 		return
@@ -196,36 +648,176 @@ func (man *Manifest) writeAction(bw *binio.Writer, action *builds.Action) {
 		man.writeValue(bw, action.Prev)
 		man.writeValue(bw, action.Next)
 	}
+	if len(action.Extension) > 0 {
+		man.writeExtension(bw, action.Extension)
+	}
+}
+
+// metadataElementTypes are the types of elements of metadata actions, in
+// order of their encoding.
+var metadataElementTypes = [...]string{"Class", "Member", "Enum", "EnumItem"}
+
+func (man *Manifest) readMetadataAction(br *reader, action *builds.MetadataAction) {
+	var data uint8
+	if !br.Number(&data) {
+		return
+	}
+	if binio.GetBits(uint64(data), 0, 2) > 2 {
+		br.Err = errors.New("invalid action type")
+		return
+	}
+	action.Type = patch.Type(binio.GetBits(uint64(data), 0, 2) - 1)
+	element := binio.GetBits(uint64(data), 2, 4)
+	action.Element.Type = metadataElementTypes[element]
+	if element%2 == 1 {
+		br.String(&action.Element.Parent)
+	}
+	br.String(&action.Element.Name)
+	br.String(&action.Field)
+	if action.Type != patch.Add {
+		br.String(&action.Prev)
+	}
+	if action.Type != patch.Remove {
+		br.String(&action.Next)
+	}
 }
 
-func (man *Manifest) readClass(br *binio.Reader, p **rbxapijson.Class) {
+func (man *Manifest) writeMetadataAction(bw *writer, action *builds.MetadataAction) {
+	element := -1
+	for i, typ := range metadataElementTypes {
+		if action.Element.Type == typ {
+			element = i
+			break
+		}
+	}
+	if element < 0 {
+		bw.Err = errors.New("invalid metadata element type")
+		return
+	}
+	var data uint64
+	data = binio.SetBits(data, 0, 2, int(action.Type)+1)
+	data = binio.SetBits(data, 2, 4, element)
+	bw.Number(uint8(data))
+	if element%2 == 1 {
+		bw.String(action.Element.Parent)
+	}
+	bw.String(action.Element.Name)
+	bw.String(action.Field)
+	if action.Type != patch.Add {
+		bw.String(action.Prev)
+	}
+	if action.Type != patch.Remove {
+		bw.String(action.Next)
+	}
+}
+
+// readExtension reads extended fields. Elements are encoded like those of
+// metadata actions, and each field is encoded as a value.
+func (man *Manifest) readExtension(br *reader, p *apiext.Root) {
+	var length uint32
+	br.Length(&length)
+	ext := make(apiext.Root, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var element uint8
+		if !br.Number(&element) {
+			return
+		}
+		if int(element) >= len(metadataElementTypes) {
+			br.Err = errors.New("invalid extension element type")
+			return
+		}
+		key := apiext.Key{Type: metadataElementTypes[element]}
+		if element%2 == 1 {
+			br.String(&key.Parent)
+		}
+		br.String(&key.Name)
+		var n uint32
+		br.Length(&n)
+		fields := make(apiext.Fields, capHint(n))
+		for j := uint32(0); j < n; j++ {
+			var name string
+			var value *builds.Value
+			br.String(&name)
+			man.readValue(br, &value)
+			if br.Err != nil {
+				return
+			}
+			if value == nil {
+				fields[name] = nil
+			} else {
+				fields[name] = value.V
+			}
+		}
+		ext[key] = fields
+	}
+	*p = ext
+}
+
+func (man *Manifest) writeExtension(bw *writer, ext apiext.Root) {
+	keys := ext.Keys()
+	bw.Length(len(keys))
+	for _, key := range keys {
+		element := -1
+		for i, typ := range metadataElementTypes {
+			if key.Type == typ {
+				element = i
+				break
+			}
+		}
+		if element < 0 {
+			bw.Err = errors.New("invalid extension element type")
+			return
+		}
+		bw.Number(uint8(element))
+		if element%2 == 1 {
+			bw.String(key.Parent)
+		}
+		bw.String(key.Name)
+		fields := ext[key]
+		names := fields.AllNames()
+		bw.Length(len(names))
+		for _, name := range names {
+			bw.String(name)
+			if v := fields[name]; v == nil {
+				man.writeValue(bw, nil)
+			} else {
+				man.writeValue(bw, &builds.Value{V: v})
+			}
+		}
+		if bw.Err != nil {
+			return
+		}
+	}
+}
+
+func (man *Manifest) readClass(br *reader, p **rbxapijson.Class) {
 	class := rbxapijson.Class{}
 	br.String(&class.Name)
 	br.String(&class.Superclass)
 	br.String(&class.MemoryCategory)
 	var length uint32
-	br.Number(&length)
-	class.Members = make([]rbxapi.Member, length)
-	for i := range class.Members {
+	br.Length(&length)
+	class.Members = make([]rbxapi.Member, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
 		var memberType uint8
 		br.Number(&memberType)
 		switch memberType {
 		case 0:
 			var member *rbxapijson.Property
 			man.readProperty(br, &member)
-			class.Members[i] = member
+			class.Members = append(class.Members, member)
 		case 1:
 			var member *rbxapijson.Function
 			man.readFunction(br, &member)
-			class.Members[i] = member
+			class.Members = append(class.Members, member)
 		case 2:
 			var member *rbxapijson.Event
 			man.readEvent(br, &member)
-			class.Members[i] = member
+			class.Members = append(class.Members, member)
 		case 3:
 			var member *rbxapijson.Callback
 			man.readCallback(br, &member)
-			class.Members[i] = member
+			class.Members = append(class.Members, member)
 		default:
 			br.Err = errors.New("invalid member type")
 		}
@@ -239,11 +831,11 @@ func (man *Manifest) readClass(br *binio.Reader, p **rbxapijson.Class) {
 	*p = &class
 }
 
-func (man *Manifest) writeClass(bw *binio.Writer, class *rbxapijson.Class) {
+func (man *Manifest) writeClass(bw *writer, class *rbxapijson.Class) {
 	bw.String(class.Name)
 	bw.String(class.Superclass)
 	bw.String(class.MemoryCategory)
-	bw.Number(uint32(len(class.Members)))
+	bw.Length(len(class.Members))
 	for _, member := range class.Members {
 		switch member := member.(type) {
 		case *rbxapijson.Property:
@@ -263,7 +855,7 @@ func (man *Manifest) writeClass(bw *binio.Writer, class *rbxapijson.Class) {
 	man.writeTags(bw, []string(class.Tags))
 }
 
-func (man *Manifest) readProperty(br *binio.Reader, p **rbxapijson.Property) {
+func (man *Manifest) readProperty(br *reader, p **rbxapijson.Property) {
 	member := rbxapijson.Property{}
 	br.String(&member.Name)
 	br.String(&member.ValueType.Category)
@@ -281,7 +873,7 @@ func (man *Manifest) readProperty(br *binio.Reader, p **rbxapijson.Property) {
 	*p = &member
 }
 
-func (man *Manifest) writeProperty(bw *binio.Writer, member *rbxapijson.Property) {
+func (man *Manifest) writeProperty(bw *writer, member *rbxapijson.Property) {
 	bw.String(member.Name)
 	bw.String(member.ValueType.Category)
 	bw.String(member.ValueType.Name)
@@ -295,7 +887,7 @@ func (man *Manifest) writeProperty(bw *binio.Writer, member *rbxapijson.Property
 	man.writeTags(bw, []string(member.Tags))
 }
 
-func (man *Manifest) readFunction(br *binio.Reader, p **rbxapijson.Function) {
+func (man *Manifest) readFunction(br *reader, p **rbxapijson.Function) {
 	member := rbxapijson.Function{}
 	br.String(&member.Name)
 	man.readParameters(br, &member.Parameters)
@@ -308,7 +900,7 @@ func (man *Manifest) readFunction(br *binio.Reader, p **rbxapijson.Function) {
 	*p = &member
 }
 
-func (man *Manifest) writeFunction(bw *binio.Writer, member *rbxapijson.Function) {
+func (man *Manifest) writeFunction(bw *writer, member *rbxapijson.Function) {
 	bw.String(member.Name)
 	man.writeParameters(bw, member.Parameters)
 	bw.String(member.ReturnType.Category)
@@ -317,7 +909,7 @@ func (man *Manifest) writeFunction(bw *binio.Writer, member *rbxapijson.Function
 	man.writeTags(bw, []string(member.Tags))
 }
 
-func (man *Manifest) readEvent(br *binio.Reader, p **rbxapijson.Event) {
+func (man *Manifest) readEvent(br *reader, p **rbxapijson.Event) {
 	member := rbxapijson.Event{}
 	br.String(&member.Name)
 	man.readParameters(br, &member.Parameters)
@@ -328,14 +920,14 @@ func (man *Manifest) readEvent(br *binio.Reader, p **rbxapijson.Event) {
 	*p = &member
 }
 
-func (man *Manifest) writeEvent(bw *binio.Writer, member *rbxapijson.Event) {
+func (man *Manifest) writeEvent(bw *writer, member *rbxapijson.Event) {
 	bw.String(member.Name)
 	man.writeParameters(bw, member.Parameters)
 	bw.String(member.Security)
 	man.writeTags(bw, []string(member.Tags))
 }
 
-func (man *Manifest) readCallback(br *binio.Reader, p **rbxapijson.Callback) {
+func (man *Manifest) readCallback(br *reader, p **rbxapijson.Callback) {
 	member := rbxapijson.Callback{}
 	br.String(&member.Name)
 	man.readParameters(br, &member.Parameters)
@@ -348,7 +940,7 @@ func (man *Manifest) readCallback(br *binio.Reader, p **rbxapijson.Callback) {
 	*p = &member
 }
 
-func (man *Manifest) writeCallback(bw *binio.Writer, member *rbxapijson.Callback) {
+func (man *Manifest) writeCallback(bw *writer, member *rbxapijson.Callback) {
 	bw.String(member.Name)
 	man.writeParameters(bw, member.Parameters)
 	bw.String(member.ReturnType.Category)
@@ -357,11 +949,12 @@ func (man *Manifest) writeCallback(bw *binio.Writer, member *rbxapijson.Callback
 	man.writeTags(bw, []string(member.Tags))
 }
 
-func (man *Manifest) readParameters(br *binio.Reader, params *[]rbxapijson.Parameter) {
+func (man *Manifest) readParameters(br *reader, params *[]rbxapijson.Parameter) {
 	var length uint32
-	br.Number(&length)
-	*params = make([]rbxapijson.Parameter, length)
-	for i, param := range *params {
+	br.Length(&length)
+	*params = make([]rbxapijson.Parameter, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var param rbxapijson.Parameter
 		br.String(&param.Type.Category)
 		br.String(&param.Type.Name)
 		br.String(&param.Name)
@@ -371,12 +964,15 @@ func (man *Manifest) readParameters(br *binio.Reader, params *[]rbxapijson.Param
 			param.HasDefault = true
 			br.String(&param.Default)
 		}
-		(*params)[i] = param
+		if br.Err != nil {
+			return
+		}
+		*params = append(*params, param)
 	}
 }
 
-func (man *Manifest) writeParameters(bw *binio.Writer, params []rbxapijson.Parameter) {
-	bw.Number(uint32(len(params)))
+func (man *Manifest) writeParameters(bw *writer, params []rbxapijson.Parameter) {
+	bw.Length(len(params))
 	for _, param := range params {
 		bw.String(param.Type.Category)
 		bw.String(param.Type.Name)
@@ -390,14 +986,19 @@ func (man *Manifest) writeParameters(bw *binio.Writer, params []rbxapijson.Param
 	}
 }
 
-func (man *Manifest) readEnum(br *binio.Reader, p **rbxapijson.Enum) {
+func (man *Manifest) readEnum(br *reader, p **rbxapijson.Enum) {
 	enum := rbxapijson.Enum{}
 	br.String(&enum.Name)
 	var length uint32
-	br.Number(&length)
-	enum.Items = make([]*rbxapijson.EnumItem, length)
-	for i := range enum.Items {
-		man.readEnumItem(br, &enum.Items[i])
+	br.Length(&length)
+	enum.Items = make([]*rbxapijson.EnumItem, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var item *rbxapijson.EnumItem
+		man.readEnumItem(br, &item)
+		if br.Err != nil {
+			return
+		}
+		enum.Items = append(enum.Items, item)
 	}
 	var tags []string
 	man.readTags(br, &tags)
@@ -405,46 +1006,47 @@ func (man *Manifest) readEnum(br *binio.Reader, p **rbxapijson.Enum) {
 	*p = &enum
 }
 
-func (man *Manifest) writeEnum(bw *binio.Writer, enum *rbxapijson.Enum) {
+func (man *Manifest) writeEnum(bw *writer, enum *rbxapijson.Enum) {
 	bw.String(enum.Name)
-	bw.Number(uint32(len(enum.Items)))
+	bw.Length(len(enum.Items))
 	for _, item := range enum.Items {
 		man.writeEnumItem(bw, item)
 	}
 	man.writeTags(bw, []string(enum.Tags))
 }
 
-func (man *Manifest) readEnumItem(br *binio.Reader, p **rbxapijson.EnumItem) {
+func (man *Manifest) readEnumItem(br *reader, p **rbxapijson.EnumItem) {
 	item := rbxapijson.EnumItem{}
 	br.String(&item.Name)
-	var value uint32
-	br.Number(&value)
-	item.Value = int(value)
+	br.Int(&item.Value)
 	var tags []string
 	man.readTags(br, &tags)
 	item.Tags = rbxapijson.Tags(tags)
 	*p = &item
 }
 
-func (man *Manifest) writeEnumItem(bw *binio.Writer, item *rbxapijson.EnumItem) {
+func (man *Manifest) writeEnumItem(bw *writer, item *rbxapijson.EnumItem) {
 	bw.String(item.Name)
-	bw.Number(uint32(item.Value))
+	bw.Int(item.Value)
 	man.writeTags(bw, []string(item.Tags))
 }
 
-func (man *Manifest) readValue(br *binio.Reader, p **builds.Value) {
+func (man *Manifest) readValue(br *reader, p **builds.Value) {
 	value := builds.Value{}
 	var valueType uint8
 	br.Number(&valueType)
 	switch valueType {
+	case 0:
+		*p = nil
+		return
 	case 1:
 		value.V = false
 	case 2:
 		value.V = true
 	case 3:
-		var v uint32
-		br.Number(&v)
-		value.V = int(v)
+		var v int
+		br.Int(&v)
+		value.V = v
 	case 4:
 		var v string
 		br.String(&v)
@@ -462,11 +1064,18 @@ func (man *Manifest) readValue(br *binio.Reader, p **builds.Value) {
 		var v []rbxapijson.Parameter
 		man.readParameters(br, &v)
 		value.V = rbxapijson.Parameters{List: &v}
+	default:
+		br.Err = errors.New("invalid value type")
+		return
 	}
 	*p = &value
 }
 
-func (man *Manifest) writeValue(bw *binio.Writer, value *builds.Value) {
+func (man *Manifest) writeValue(bw *writer, value *builds.Value) {
+	if value == nil {
+		bw.Number(uint8(0))
+		return
+	}
 	switch value := value.V.(type) {
 	case bool:
 		if !value {
@@ -476,7 +1085,7 @@ func (man *Manifest) writeValue(bw *binio.Writer, value *builds.Value) {
 		}
 	case int:
 		bw.Number(uint8(3))
-		bw.Number(uint32(value))
+		bw.Int(value)
 	case string:
 		bw.Number(uint8(4))
 		bw.String(value)
@@ -497,20 +1106,33 @@ func (man *Manifest) writeValue(bw *binio.Writer, value *builds.Value) {
 	}
 }
 
-func (man *Manifest) readTags(br *binio.Reader, tags *[]string) {
+func (man *Manifest) readTags(br *reader, tags *[]string) {
+	*tags = man.readStrings(br)
+}
+
+func (man *Manifest) writeTags(bw *writer, tags []string) {
+	man.writeStrings(bw, tags)
+}
+
+// readStrings reads a list of strings.
+func (man *Manifest) readStrings(br *reader) []string {
 	var length uint32
-	br.Number(&length)
-	*tags = make([]string, length)
-	for i, tag := range *tags {
-		br.String(&tag)
-		(*tags)[i] = tag
+	br.Length(&length)
+	list := make([]string, 0, capHint(length))
+	for i := uint32(0); i < length; i++ {
+		var s string
+		if !br.String(&s) {
+			break
+		}
+		list = append(list, s)
 	}
+	return list
 }
 
-func (man *Manifest) writeTags(bw *binio.Writer, tags []string) {
-	bw.Number(uint32(len(tags)))
-	for _, tag := range tags {
-		bw.String(tag)
+func (man *Manifest) writeStrings(bw *writer, list []string) {
+	bw.Length(len(list))
+	for _, s := range list {
+		bw.String(s)
 	}
 }
 
@@ -0,0 +1,468 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxapi/patch"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+	"github.com/robloxapi/rbxapiref/builds"
+	"github.com/robloxapi/rbxapiref/fetch"
+	"github.com/robloxapi/rbxapiref/internal/binio"
+)
+
+func testInfo(hash string, day int) builds.Info {
+	return builds.Info{
+		Hash:    hash,
+		Date:    time.Date(2020, 1, day, 12, 0, 0, 0, time.UTC),
+		Version: fetch.Version{Major: 0, Minor: 400 + day, Maint: 0, Build: 1000 + day},
+	}
+}
+
+// testManifest returns a manifest that uses every part of the format.
+func testManifest() *Manifest {
+	info1 := testInfo("version-1", 1)
+	info2 := testInfo("version-2", 2)
+	info3 := testInfo("version-3", 3)
+	part := &rbxapijson.Class{
+		Name:           "Part",
+		Superclass:     "BasePart",
+		MemoryCategory: "Instances",
+		Members: []rbxapi.Member{
+			&rbxapijson.Property{
+				Name:          "Size",
+				ValueType:     rbxapijson.Type{Category: "DataType", Name: "Vector3"},
+				Category:      "Part",
+				ReadSecurity:  "None",
+				WriteSecurity: "PluginSecurity",
+				CanLoad:       true,
+				Tags:          rbxapijson.Tags{"NotReplicated"},
+			},
+			&rbxapijson.Function{
+				Name: "Resize",
+				Parameters: []rbxapijson.Parameter{
+					{Type: rbxapijson.Type{Category: "Enum", Name: "NormalId"}, Name: "normal"},
+					{Type: rbxapijson.Type{Category: "Primitive", Name: "int"}, Name: "deltaAmount", Default: "-1"},
+				},
+				ReturnType: rbxapijson.Type{Category: "Primitive", Name: "bool"},
+				Security:   "None",
+				Tags:       rbxapijson.Tags{},
+			},
+		},
+		Tags: rbxapijson.Tags{"NotCreatable"},
+	}
+	material := &rbxapijson.Enum{
+		Name: "Material",
+		Items: []*rbxapijson.EnumItem{
+			{Name: "Plastic", Value: 256, Tags: rbxapijson.Tags{}},
+			{Name: "Air", Value: -1, Tags: rbxapijson.Tags{"Deprecated"}},
+		},
+		Tags: rbxapijson.Tags{},
+	}
+	record := builds.Settings{
+		Configs: map[string]fetch.Config{
+			"Production": {APIDump: []fetch.Location{{Format: ".json"}}},
+			"Archive":    {ReflectionMetadata: []fetch.Location{{Format: ".xml"}}},
+		},
+		UseConfigs: []string{"Archive", "Production"},
+		Channel:    "production",
+		Channels:   map[string][]string{"beta": {"Production"}},
+		Rewind:     builds.RewindPreview,
+		Metadata:   true,
+	}.Record()
+	return &Manifest{
+		Settings: &record,
+		Patches: []builds.Patch{
+			{
+				Info:    info1,
+				Aliases: []builds.Info{testInfo("version-1b", 1)},
+				Config:  "Archive",
+				Actions: []builds.Action{
+					{Type: patch.Add, Class: part, Extension: apiext.Root{
+						apiext.ClassKey("Part"): {"Tags.PreferredParent": "Workspace"},
+					}},
+					{Type: patch.Add, Enum: material},
+				},
+				HasMetadata: true,
+				Metadata:    []builds.MetadataAction{},
+			},
+			{
+				Prev:   &info1,
+				Info:   info2,
+				Config: "Production",
+				Source: &builds.Source{
+					Location:    "https://example.com/version-2-API-Dump.json",
+					Time:        time.Date(2020, 1, 2, 13, 0, 0, 0, time.UTC),
+					Fingerprint: "abc123",
+				},
+				Actions: []builds.Action{
+					{Type: patch.Change, Class: part, Property: part.Members[0].(*rbxapijson.Property), Field: "CanLoad", Prev: &builds.Value{V: false}, Next: &builds.Value{V: true}},
+					{Type: patch.Change, Enum: material, EnumItem: material.Items[1], Field: "Value", Prev: &builds.Value{V: -5}, Next: &builds.Value{V: -1}},
+					{Type: patch.Change, Class: part, Field: "Tags", Prev: &builds.Value{V: []string{}}, Next: &builds.Value{V: []string{"NotCreatable"}}},
+					{Type: patch.Change, Class: part, Function: part.Members[1].(*rbxapijson.Function), Field: "ReturnType", Prev: &builds.Value{V: rbxapijson.Type{Category: "Primitive", Name: "void"}}, Next: &builds.Value{V: rbxapijson.Type{Category: "Primitive", Name: "bool"}}},
+					{Type: patch.Change, Class: part, Function: part.Members[1].(*rbxapijson.Function), Field: "Parameters", Prev: &builds.Value{V: rbxapijson.Parameters{List: &[]rbxapijson.Parameter{}}}, Next: &builds.Value{V: rbxapijson.Parameters{List: &part.Members[1].(*rbxapijson.Function).Parameters}}},
+					{Type: patch.Change, Class: part, Field: "MemoryCategory", Prev: &builds.Value{V: "Internal"}, Next: &builds.Value{V: "Instances"}},
+				},
+				HasMetadata: true,
+				Metadata: []builds.MetadataAction{
+					{Type: patch.Add, Element: builds.MetadataElement{Type: "Class", Name: "Part"}, Field: "summary", Next: "A part."},
+					{Type: patch.Change, Element: builds.MetadataElement{Type: "Member", Parent: "Part", Name: "Size"}, Field: "Browsable", Prev: "false", Next: "true"},
+					{Type: patch.Remove, Element: builds.MetadataElement{Type: "EnumItem", Parent: "Material", Name: "Air"}, Field: "deprecated", Prev: "true"},
+				},
+				Extension: apiext.Root{
+					apiext.MemberKey("Part", "Size"):      {"ThreadSafety": "ReadSafe", "Capabilities": []string{"Basic"}},
+					apiext.EnumItemKey("Material", "Air"): {"Tags.Replacement": nil},
+				},
+			},
+		},
+		Channels: []Channel{
+			{Name: "beta", Patches: []builds.Patch{
+				{
+					Info:       info3,
+					Config:     "Production",
+					Unreleased: true,
+					Actions: []builds.Action{
+						{Type: patch.Remove, Class: part, Event: &rbxapijson.Event{Name: "Touched", Parameters: []rbxapijson.Parameter{}, Security: "None", Tags: rbxapijson.Tags{}}},
+						{Type: patch.Remove, Enum: material, EnumItem: material.Items[0]},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func encodeJSON(t *testing.T, man *Manifest) string {
+	var buf bytes.Buffer
+	if err := EncodeJSON(&buf, man); err != nil {
+		t.Fatalf("encode JSON: %v", err)
+	}
+	return buf.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest *Manifest
+	}{
+		{"empty", &Manifest{Patches: []builds.Patch{}}},
+		{"empty patch", &Manifest{Patches: []builds.Patch{{Info: testInfo("version-1", 1), Actions: []builds.Action{}}}}},
+		{"full", testManifest()},
+	}
+	for _, test := range tests {
+		want := encodeJSON(t, test.manifest)
+		for _, compression := range []Compression{CompressNone, CompressGzip, CompressZstd} {
+			test.manifest.Compression = compression
+			var buf bytes.Buffer
+			if err := Encode(&buf, test.manifest); err != nil {
+				t.Errorf("%s/%s: encode: %v", test.name, compression, err)
+				continue
+			}
+			man, err := Decode(&buf)
+			if err != nil {
+				t.Errorf("%s/%s: decode: %v", test.name, compression, err)
+				continue
+			}
+			if man.Format != FormatVersion {
+				t.Errorf("%s/%s: expected format %d, got %d", test.name, compression, FormatVersion, man.Format)
+			}
+			if man.Tool != Tool {
+				t.Errorf("%s/%s: expected tool %q, got %q", test.name, compression, Tool, man.Tool)
+			}
+			if man.Compression != compression {
+				t.Errorf("%s/%s: expected compression %s, got %s", test.name, compression, compression, man.Compression)
+			}
+			if got := encodeJSON(t, man); got != want {
+				t.Errorf("%s/%s: manifest differs after round trip:\n\tgot  %s\n\twant %s", test.name, compression, got, want)
+			}
+		}
+	}
+}
+
+// encoder builds raw manifest files for testing the decoder.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) uvarint(v uint64) *encoder {
+	var b [binary.MaxVarintLen64]byte
+	e.Write(b[:binary.PutUvarint(b[:], v)])
+	return e
+}
+
+func (e *encoder) bytes(b ...byte) *encoder {
+	e.Write(b)
+	return e
+}
+
+func (e *encoder) string(s string) *encoder {
+	e.uvarint(uint64(len(s)))
+	e.WriteString(s)
+	return e
+}
+
+func (e *encoder) uint32(v uint32) *encoder {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	e.Write(b[:])
+	return e
+}
+
+// header starts a manifest of the given format version and compression.
+func header(version uint16, compression Compression) *encoder {
+	e := &encoder{}
+	e.WriteString(Magic)
+	e.bytes(byte(version), byte(version>>8))
+	e.string("test")
+	e.bytes(byte(compression))
+	return e
+}
+
+// tables starts an uncompressed manifest of the current format with the given
+// string table, and no classes or enums.
+func tables(strs ...string) *encoder {
+	e := header(FormatVersion, CompressNone)
+	e.uvarint(uint64(len(strs)))
+	for _, s := range strs {
+		e.string(s)
+	}
+	return e.uvarint(0).uvarint(0)
+}
+
+// testStrings is a string table referred to by patchStart.
+func testStrings() []string {
+	date, _ := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).MarshalBinary()
+	return []string{"version-1", string(date), "Production"}
+}
+
+// patchStart encodes the start of a patch without flags, up to its list of
+// actions.
+func (e *encoder) patchStart() *encoder {
+	return e.uvarint(0).uvarint(1).uint32(0).uint32(1).uint32(2).uint32(3).bytes(0).uvarint(2)
+}
+
+func TestDecodeLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		// patches is the number of patches recovered before the error.
+		patches int
+		// decode is whether a *DecodeError is expected, with the patch and
+		// action indices of the error.
+		decode bool
+		patch  int
+		action int
+		// err is an error expected to be wrapped by the returned error.
+		err error
+	}{
+		{
+			name: "newer version",
+			data: header(FormatVersion+1, CompressNone).Bytes(),
+			err:  &VersionError{},
+		},
+		{
+			name: "unknown compression",
+			data: header(FormatVersion, 200).Bytes(),
+		},
+		{
+			name:   "empty content",
+			data:   header(FormatVersion, CompressNone).Bytes(),
+			decode: true, patch: -1, action: -1,
+			err: io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "string table exceeds limit",
+			data:   header(FormatVersion, CompressNone).uvarint(maxLength + 1).Bytes(),
+			decode: true, patch: -1, action: -1,
+		},
+		{
+			name:   "string exceeds limit",
+			data:   header(FormatVersion, CompressNone).uvarint(1).uvarint(maxLength + 1).Bytes(),
+			decode: true, patch: -1, action: -1,
+			err: binio.ErrOverflow,
+		},
+		{
+			name:   "string longer than content",
+			data:   header(FormatVersion, CompressNone).uvarint(1).uvarint(maxLength).string("abc").Bytes(),
+			decode: true, patch: -1, action: -1,
+			err: io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "varint overflow",
+			data:   header(FormatVersion, CompressNone).bytes(0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F).Bytes(),
+			decode: true, patch: -1, action: -1,
+			err: binio.ErrOverflow,
+		},
+		{
+			name:   "patch count exceeds limit",
+			data:   tables().bytes(0).uvarint(maxLength + 1).Bytes(),
+			decode: true, patch: -1, action: -1,
+		},
+		{
+			name:   "patch count larger than content",
+			data:   tables().bytes(0).uvarint(maxLength).Bytes(),
+			decode: true, patch: 0, action: -1,
+			err: io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "string index out of range",
+			data:   tables().bytes(0).uvarint(1).patchStart().Bytes(),
+			decode: true, patch: 0, action: -1,
+		},
+		{
+			name:   "invalid rewind mode",
+			data:   tables("").bytes(1).uvarint(0).uvarint(0).uvarint(0).uvarint(0).bytes(200).Bytes(),
+			decode: true, patch: -1, action: -1,
+		},
+		{
+			name:   "action count larger than content",
+			data:   tables(testStrings()...).bytes(0).uvarint(1).patchStart().uvarint(maxLength).Bytes(),
+			decode: true, patch: 0, action: 0,
+			err: io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "invalid action type",
+			data:   tables(testStrings()...).bytes(0).uvarint(1).patchStart().uvarint(1).bytes(0xFF).Bytes(),
+			decode: true, patch: 0, action: 0,
+		},
+		{
+			name:   "class index out of range",
+			data:   tables(testStrings()...).bytes(0).uvarint(1).patchStart().uvarint(1).bytes(2 | 5<<2).uvarint(0).Bytes(),
+			decode: true, patch: 0, action: 0,
+		},
+		{
+			name: "recover patches before corruption",
+			data: tables(testStrings()...).bytes(0).uvarint(3).
+				patchStart().uvarint(0).
+				patchStart().uvarint(0).
+				patchStart().uvarint(1).bytes(0xFF).Bytes(),
+			patches: 2,
+			decode:  true, patch: 2, action: 0,
+		},
+		{
+			name:   "channel count larger than content",
+			data:   tables().bytes(0).uvarint(0).uvarint(maxLength).Bytes(),
+			decode: true, patch: -1, action: -1,
+			err: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, test := range tests {
+		man, err := Decode(bytes.NewReader(test.data))
+		if err == nil {
+			t.Errorf("%s: expected error", test.name)
+			continue
+		}
+		if len(man.Patches) != test.patches {
+			t.Errorf("%s: expected %d recovered patches, got %d", test.name, test.patches, len(man.Patches))
+		}
+		var derr *DecodeError
+		if errors.As(err, &derr) != test.decode {
+			t.Errorf("%s: unexpected error type %T: %v", test.name, err, err)
+			continue
+		}
+		if test.decode && (derr.Patch != test.patch || derr.Action != test.action) {
+			t.Errorf("%s: expected error at patch %d, action %d, got %v", test.name, test.patch, test.action, err)
+		}
+		switch test.err.(type) {
+		case nil:
+		case *VersionError:
+			var verr *VersionError
+			if !errors.As(err, &verr) {
+				t.Errorf("%s: expected version error, got %v", test.name, err)
+			}
+		default:
+			if !errors.Is(err, test.err) {
+				t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+			}
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	for _, compression := range []Compression{CompressNone, CompressGzip, CompressZstd} {
+		man := testManifest()
+		man.Compression = compression
+		var buf bytes.Buffer
+		if err := Encode(&buf, man); err != nil {
+			t.Fatalf("%s: encode: %v", compression, err)
+		}
+		data := buf.Bytes()
+		for n := 0; n < len(data); n++ {
+			if _, err := Decode(bytes.NewReader(data[:n])); err == nil {
+				t.Errorf("%s: expected error when truncated to %d of %d bytes", compression, n, len(data))
+			}
+		}
+		// Corrupt the checksum at the end of the compressed stream.
+		if compression != CompressNone {
+			data[len(data)-1] ^= 0xFF
+			if _, err := Decode(bytes.NewReader(data)); err == nil {
+				t.Errorf("%s: expected error with corrupt checksum", compression)
+			}
+		}
+	}
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	tests := []struct {
+		// file is the name of the fixture in testdata, without extension.
+		// The ".bin" file is decoded and compared with the ".json" file.
+		file        string
+		format      int
+		compression Compression
+	}{
+		// Written before the header, without channels.
+		{file: "format0", format: 0},
+		// Written before the header, with channels.
+		{file: "format0-channels", format: 0},
+		{file: "format1", format: 1, compression: CompressGzip},
+	}
+	for _, test := range tests {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", test.file+".bin"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := ioutil.ReadFile(filepath.Join("testdata", test.file+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		man, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: decode: %v", test.file, err)
+			continue
+		}
+		if man.Format != test.format {
+			t.Errorf("%s: expected format %d, got %d", test.file, test.format, man.Format)
+		}
+		if man.Compression != test.compression {
+			t.Errorf("%s: expected compression %s, got %s", test.file, test.compression, man.Compression)
+		}
+		if got := encodeJSON(t, man); got != string(want) {
+			t.Errorf("%s: decoded manifest differs:\n\tgot  %s\n\twant %s", test.file, got, want)
+			continue
+		}
+
+		// Upgrade to the current format.
+		var buf bytes.Buffer
+		if err := Encode(&buf, man); err != nil {
+			t.Errorf("%s: encode: %v", test.file, err)
+			continue
+		}
+		if man, err = Decode(&buf); err != nil {
+			t.Errorf("%s: decode upgraded: %v", test.file, err)
+			continue
+		}
+		if man.Format != FormatVersion {
+			t.Errorf("%s: expected upgraded format %d, got %d", test.file, FormatVersion, man.Format)
+		}
+		if got := encodeJSON(t, man); got != string(want) {
+			t.Errorf("%s: upgraded manifest differs:\n\tgot  %s\n\twant %s", test.file, got, want)
+		}
+	}
+}
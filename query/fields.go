@@ -0,0 +1,365 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxapiref/apiext"
+	"github.com/robloxapi/rbxapiref/builds"
+	"github.com/robloxapi/rbxapiref/entities"
+)
+
+// commonFields are the fields of every kind of entity other than types.
+var commonFields = []string{"name", "tag", "channel", "removed", "documented"}
+
+// kindFields maps each kind to the fields specific to it.
+var kindFields = map[Kind][]string{
+	KindClass:    {"superclass", "memorycategory", "members"},
+	KindMember:   {"membertype", "class", "security", "readsecurity", "writesecurity", "valuetype", "returntype", "category", "canload", "cansave", "parameters"},
+	KindEnum:     {"items"},
+	KindEnumItem: {"enum", "value"},
+}
+
+// lookupField returns the name of the field of the given kind that matches
+// name case-insensitively. Extended fields of the apiext package are
+// available to every kind other than types.
+func lookupField(kind Kind, name string) (field string, ok bool) {
+	lower := strings.ToLower(name)
+	if kind == KindType {
+		switch lower {
+		case "name", "category", "removed", "documented":
+			return lower, true
+		}
+		return "", false
+	}
+	if kind.isMember() {
+		kind = KindMember
+	}
+	for _, list := range [][]string{commonFields, kindFields[kind]} {
+		for _, f := range list {
+			if f == lower {
+				return f, true
+			}
+		}
+	}
+	return apiext.LookupField(name)
+}
+
+func boolValue(b bool) []string {
+	return []string{strconv.FormatBool(b)}
+}
+
+// fieldValues returns the values of a field of an entity. Returns nil if the
+// entity does not have the field.
+func fieldValues(entity interface{}, field string) []string {
+	if apiext.IsField(field) {
+		var ext apiext.Fields
+		switch e := entity.(type) {
+		case *entities.Class:
+			ext = e.Extension
+		case *entities.Member:
+			ext = e.Extension
+		case *entities.Enum:
+			ext = e.Extension
+		case *entities.EnumItem:
+			ext = e.Extension
+		}
+		// The names of tag objects are not known in advance, so they may
+		// differ in case from the query.
+		for name, value := range ext {
+			if !strings.EqualFold(name, field) {
+				continue
+			}
+			switch v := value.(type) {
+			case string:
+				return []string{v}
+			case []string:
+				return v
+			}
+		}
+		return nil
+	}
+	switch e := entity.(type) {
+	case *entities.Class:
+		switch field {
+		case "name":
+			return []string{e.ID}
+		case "superclass":
+			return []string{e.Element.Superclass}
+		case "memorycategory":
+			return []string{e.Element.MemoryCategory}
+		case "members":
+			n := 0
+			for _, member := range e.MemberList {
+				if !member.Removed {
+					n++
+				}
+			}
+			return []string{strconv.Itoa(n)}
+		case "tag":
+			return e.Element.GetTags()
+		case "channel":
+			return e.Channels
+		case "removed":
+			return boolValue(e.Removed)
+		case "documented":
+			return boolValue(e.Document != nil)
+		}
+	case *entities.Member:
+		return memberValues(e, field)
+	case *entities.Enum:
+		switch field {
+		case "name":
+			return []string{e.ID}
+		case "items":
+			n := 0
+			for _, item := range e.ItemList {
+				if !item.Removed {
+					n++
+				}
+			}
+			return []string{strconv.Itoa(n)}
+		case "tag":
+			return e.Element.GetTags()
+		case "channel":
+			return e.Channels
+		case "removed":
+			return boolValue(e.Removed)
+		case "documented":
+			return boolValue(e.Document != nil)
+		}
+	case *entities.EnumItem:
+		switch field {
+		case "name":
+			return []string{e.ID[1]}
+		case "enum":
+			return []string{e.ID[0]}
+		case "value":
+			return []string{strconv.Itoa(e.Element.Value)}
+		case "tag":
+			return e.Element.GetTags()
+		case "channel":
+			return e.Channels
+		case "removed":
+			return boolValue(e.Removed || e.Parent.Removed)
+		case "documented":
+			return boolValue(e.Document != nil)
+		}
+	case *entities.Type:
+		switch field {
+		case "name":
+			return []string{e.ID}
+		case "category":
+			return []string{e.Element.Category}
+		case "removed":
+			return boolValue(e.Removed)
+		case "documented":
+			return boolValue(e.Document != nil)
+		}
+	}
+	return nil
+}
+
+func memberValues(e *entities.Member, field string) []string {
+	switch field {
+	case "name":
+		return []string{e.ID[1]}
+	case "membertype":
+		return []string{e.Element.GetMemberType()}
+	case "class":
+		return []string{e.ID[0]}
+	case "tag":
+		return e.Element.GetTags()
+	case "channel":
+		return e.Channels
+	case "removed":
+		return boolValue(e.Removed || e.Parent.Removed)
+	case "documented":
+		return boolValue(e.Document != nil)
+	}
+	switch m := e.Element.(type) {
+	case *rbxapijson.Property:
+		switch field {
+		case "security":
+			return []string{m.ReadSecurity, m.WriteSecurity}
+		case "readsecurity":
+			return []string{m.ReadSecurity}
+		case "writesecurity":
+			return []string{m.WriteSecurity}
+		case "valuetype":
+			return []string{m.ValueType.Name}
+		case "category":
+			return []string{m.Category}
+		case "canload":
+			return boolValue(m.CanLoad)
+		case "cansave":
+			return boolValue(m.CanSave)
+		}
+	case *rbxapijson.Function:
+		switch field {
+		case "security":
+			return []string{m.Security}
+		case "returntype":
+			return []string{m.ReturnType.Name}
+		case "parameters":
+			return []string{strconv.Itoa(len(m.Parameters))}
+		}
+	case *rbxapijson.Event:
+		switch field {
+		case "security":
+			return []string{m.Security}
+		case "parameters":
+			return []string{strconv.Itoa(len(m.Parameters))}
+		}
+	case *rbxapijson.Callback:
+		switch field {
+		case "security":
+			return []string{m.Security}
+		case "returntype":
+			return []string{m.ReturnType.Name}
+		case "parameters":
+			return []string{strconv.Itoa(len(m.Parameters))}
+		}
+	}
+	return nil
+}
+
+// relations maps the name of each relation to itself.
+var relations = map[string]string{
+	"inherits":      "inherits",
+	"inherited-by":  "inherited-by",
+	"references":    "references",
+	"referenced-by": "referenced-by",
+}
+
+// subjects is the set of subjects of a relation.
+var subjects = map[string]bool{
+	"class":  true,
+	"enum":   true,
+	"parent": true,
+}
+
+// subjectOf returns the subject of a relation of an entity. Returns nil if the
+// entity has no such subject.
+func subjectOf(entity interface{}, subject string) interface{} {
+	switch subject {
+	case "":
+		return entity
+	case "class":
+		switch e := entity.(type) {
+		case *entities.Class:
+			return e
+		case *entities.Member:
+			return e.Parent
+		}
+	case "enum":
+		switch e := entity.(type) {
+		case *entities.Enum:
+			return e
+		case *entities.EnumItem:
+			return e.Parent
+		}
+	case "parent":
+		switch e := entity.(type) {
+		case *entities.Member:
+			return e.Parent
+		case *entities.EnumItem:
+			return e.Parent
+		}
+	}
+	return nil
+}
+
+// matchRelation returns whether subject is related to the element referred to
+// by ref.
+func matchRelation(subject interface{}, rel string, ref builds.Ref) bool {
+	switch rel {
+	case "inherits":
+		// A class inherits from itself, as with Instance.IsA.
+		class, ok := subject.(*entities.Class)
+		if !ok || !refersToClass(ref) {
+			return false
+		}
+		if strings.EqualFold(class.ID, ref.Primary) {
+			return true
+		}
+		for _, super := range class.Superclasses {
+			if strings.EqualFold(super.ID, ref.Primary) {
+				return true
+			}
+		}
+	case "inherited-by":
+		class, ok := subject.(*entities.Class)
+		return ok && refersToClass(ref) && inheritedBy(class, ref.Primary)
+	case "references":
+		var list []entities.ElementTyper
+		switch e := subject.(type) {
+		case *entities.Class:
+			list = e.ReferenceList
+		case *entities.Member:
+			list = e.ReferenceList
+		}
+		for _, et := range list {
+			if matchType(et.ElementType(), ref) {
+				return true
+			}
+		}
+	case "referenced-by":
+		if ref.Kind == builds.RefEnum || ref.Kind == builds.RefType {
+			return false
+		}
+		var list []entities.Referrer
+		switch e := subject.(type) {
+		case *entities.Class:
+			list = e.ReferrerList
+		case *entities.Enum:
+			list = e.ReferrerList
+		case *entities.Type:
+			list = e.ReferrerList
+		}
+		for _, r := range list {
+			if strings.EqualFold(r.Member.ID[0], ref.Primary) &&
+				(ref.Secondary == "" || strings.EqualFold(r.Member.ID[1], ref.Secondary)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// refersToClass returns whether ref may refer to a class.
+func refersToClass(ref builds.Ref) bool {
+	return ref.Secondary == "" && (ref.Kind == builds.RefAny || ref.Kind == builds.RefClass)
+}
+
+// inheritedBy returns whether class is the class of the given name, or one of
+// its superclasses.
+func inheritedBy(class *entities.Class, name string) bool {
+	if strings.EqualFold(class.ID, name) {
+		return true
+	}
+	for _, sub := range class.Subclasses {
+		if inheritedBy(sub, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchType returns whether typ is the type referred to by ref.
+func matchType(typ rbxapi.Type, ref builds.Ref) bool {
+	if ref.Secondary != "" || !strings.EqualFold(typ.GetName(), ref.Primary) {
+		return false
+	}
+	switch cat := typ.GetCategory(); ref.Kind {
+	case builds.RefClass:
+		return cat == "Class"
+	case builds.RefEnum:
+		return cat == "Enum"
+	case builds.RefType:
+		return cat != "Class" && cat != "Enum"
+	}
+	return true
+}
@@ -0,0 +1,302 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/robloxapi/rbxapiref/builds"
+)
+
+// tokenType is the type of a token of a query.
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	Type  tokenType
+	Value string
+	// Pos is the byte offset of the token within the query.
+	Pos int
+}
+
+func (t token) String() string {
+	switch t.Type {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return strconv.Quote(t.Value)
+	}
+	return "\"" + t.Value + "\""
+}
+
+// opChars are the characters that make up comparison operators.
+const opChars = "=!<>~"
+
+// isSpace returns the size of the whitespace character at the start of s, or 0
+// if s does not start with whitespace.
+func isSpace(s string) int {
+	r, n := utf8.DecodeRuneInString(s)
+	if !unicode.IsSpace(r) {
+		return 0
+	}
+	return n
+}
+
+// lex splits a query into tokens.
+func lex(s string) (tokens []token, err error) {
+	for i := 0; i < len(s); {
+		if n := isSpace(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		c := s[i]
+		switch {
+		case c == '(':
+			tokens = append(tokens, token{Type: tokenOpen, Value: "(", Pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{Type: tokenClose, Value: ")", Pos: i})
+			i++
+		case c == '"' || c == '\'':
+			// Strings are not escaped, and end at the next matching quote.
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				return nil, &SyntaxError{Pos: i, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, token{Type: tokenString, Value: s[i+1 : i+1+j], Pos: i})
+			i += j + 2
+		case strings.IndexByte(opChars, c) >= 0:
+			j := i
+			for j < len(s) && strings.IndexByte(opChars, s[j]) >= 0 {
+				j++
+			}
+			op := s[i:j]
+			if _, ok := operators[op]; !ok {
+				return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unknown operator %q", op)}
+			}
+			tokens = append(tokens, token{Type: tokenOp, Value: op, Pos: i})
+			i = j
+		default:
+			j := i
+			for j < len(s) && isSpace(s[j:]) == 0 && strings.IndexByte(opChars+"()\"'", s[j]) < 0 {
+				_, n := utf8.DecodeRuneInString(s[j:])
+				j += n
+			}
+			tokens = append(tokens, token{Type: tokenWord, Value: s[i:j], Pos: i})
+			i = j
+		}
+	}
+	tokens = append(tokens, token{Type: tokenEOF, Pos: len(s)})
+	return tokens, nil
+}
+
+// SyntaxError is returned by Parse when a query is malformed.
+type SyntaxError struct {
+	// Pos is the byte offset within the query at which the error occurred.
+	Pos int
+	Msg string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query: %s at offset %d", err.Msg, err.Pos)
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	kind   Kind
+}
+
+func (p *parser) peek() token { return p.tokens[p.i] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.Type != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// keyword returns whether the next token is the given keyword, consuming it
+// if so.
+func (p *parser) keyword(word string) bool {
+	if t := p.peek(); t.Type == tokenWord && strings.EqualFold(t.Value, word) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: t.Pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses a list of terms separated by "or".
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses a list of terms separated by "and".
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (Expr, error) {
+	if p.keyword("not") {
+		x, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	}
+	t := p.next()
+	switch t.Type {
+	case tokenOpen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.Type != tokenClose {
+			return nil, p.errorf(c, "expected \")\", got %s", c)
+		}
+		return x, nil
+	case tokenWord:
+	default:
+		return nil, p.errorf(t, "expected field or relation, got %s", t)
+	}
+
+	word := strings.ToLower(t.Value)
+	if rel, ok := relations[word]; ok {
+		return p.parseRelation(t, "", rel)
+	}
+	if n := p.peek(); n.Type == tokenWord {
+		if rel, ok := relations[strings.ToLower(n.Value)]; ok {
+			if !subjects[word] {
+				return nil, p.errorf(t, "unknown subject %q", t.Value)
+			}
+			p.next()
+			return p.parseRelation(n, word, rel)
+		}
+	}
+	field, ok := lookupField(p.kind, t.Value)
+	if !ok {
+		return nil, p.errorf(t, "unknown field %q of %s", t.Value, p.kind)
+	}
+	if o := p.peek(); o.Type == tokenOp {
+		p.next()
+		v := p.next()
+		if v.Type != tokenWord && v.Type != tokenString {
+			return nil, p.errorf(v, "expected value, got %s", v)
+		}
+		cmp := Compare{Field: field, Op: o.Value, Value: v.Value}
+		if numericOps[cmp.Op] {
+			if _, err := strconv.ParseFloat(cmp.Value, 64); err != nil {
+				return nil, p.errorf(v, "operator %s requires a number", cmp.Op)
+			}
+		}
+		return cmp, nil
+	}
+	return Flag{Field: field}, nil
+}
+
+// parseRelation parses the target of a relation. t is the token of the
+// relation.
+func (p *parser) parseRelation(t token, subject string, rel string) (Expr, error) {
+	v := p.next()
+	if v.Type != tokenWord && v.Type != tokenString {
+		return nil, p.errorf(v, "expected reference after %s", t.Value)
+	}
+	ref, err := builds.ParseRef(v.Value, "")
+	if err != nil {
+		return nil, p.errorf(v, "%s", err)
+	}
+	return Relation{Subject: subject, Rel: rel, Target: ref}, nil
+}
+
+// Parse parses a query. A query has the form
+//
+//	kind [[where] expr]
+//
+// where kind is the kind of entity to select: "class", "member", "property",
+// "function", "event", "callback", "enum", "enumitem", or "type". Plural forms
+// are also accepted.
+//
+// An expression is a list of terms combined with "and", "or", and "not", and
+// grouped with parentheses. A term is one of the following:
+//
+//	field op value        Compares the value of a field.
+//	field                 Matches if the field is set and not false.
+//	[subject] rel target  Matches a relation with another element.
+//
+// The operators are "=" and "!=", which match values case-insensitively and
+// accept "*" as a wildcard, "~", which matches a substring, and "<", "<=",
+// ">", ">=", which compare numbers. A field with several values, such as tag,
+// matches if any of its values matches.
+//
+// The relations are "inherits", "inherited-by", "references", and
+// "referenced-by". The target of a relation is a reference, as parsed by
+// builds.ParseRef. The subject is "class", "enum", or "parent", which refer to
+// the class of a member or the enum of an enum item, and is the selected
+// entity itself when omitted. For example:
+//
+//	member where security=PluginSecurity and tag=Deprecated and class inherits BasePart
+//	enum referenced-by class:Humanoid
+//	class where not removed and (tag=Service or name=*Service)
+func Parse(s string) (*Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	t := p.next()
+	if t.Type != tokenWord {
+		return nil, p.errorf(t, "expected kind of entity, got %s", t)
+	}
+	kind, ok := parseKind(t.Value)
+	if !ok {
+		return nil, p.errorf(t, "unknown kind of entity %q", t.Value)
+	}
+	p.kind = kind
+	q := &Query{Kind: kind, src: strings.TrimSpace(s)}
+	if p.keyword("where") || p.peek().Type != tokenEOF {
+		if q.Where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if t := p.peek(); t.Type != tokenEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return q, nil
+}
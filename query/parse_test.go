@@ -0,0 +1,175 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		src    string
+		tokens []token
+		errPos int
+	}{
+		{
+			src:    "",
+			tokens: []token{{Type: tokenEOF, Pos: 0}},
+		},
+		{
+			src: "class where name=Part",
+			tokens: []token{
+				{Type: tokenWord, Value: "class", Pos: 0},
+				{Type: tokenWord, Value: "where", Pos: 6},
+				{Type: tokenWord, Value: "name", Pos: 12},
+				{Type: tokenOp, Value: "=", Pos: 16},
+				{Type: tokenWord, Value: "Part", Pos: 17},
+				{Type: tokenEOF, Pos: 21},
+			},
+		},
+		{
+			src: "(value>=-1)",
+			tokens: []token{
+				{Type: tokenOpen, Value: "(", Pos: 0},
+				{Type: tokenWord, Value: "value", Pos: 1},
+				{Type: tokenOp, Value: ">=", Pos: 6},
+				{Type: tokenWord, Value: "-1", Pos: 8},
+				{Type: tokenClose, Value: ")", Pos: 10},
+				{Type: tokenEOF, Pos: 11},
+			},
+		},
+		{
+			src: "name != 'a b' ~\t\"c'd\"",
+			tokens: []token{
+				{Type: tokenWord, Value: "name", Pos: 0},
+				{Type: tokenOp, Value: "!=", Pos: 5},
+				{Type: tokenString, Value: "a b", Pos: 8},
+				{Type: tokenOp, Value: "~", Pos: 14},
+				{Type: tokenString, Value: "c'd", Pos: 16},
+				{Type: tokenEOF, Pos: 21},
+			},
+		},
+		{
+			src: "enum referenced-by class:Humanoid",
+			tokens: []token{
+				{Type: tokenWord, Value: "enum", Pos: 0},
+				{Type: tokenWord, Value: "referenced-by", Pos: 5},
+				{Type: tokenWord, Value: "class:Humanoid", Pos: 19},
+				{Type: tokenEOF, Pos: 33},
+			},
+		},
+		{
+			src: "name=à\vvalue\u00a0=\u2003\x85",
+			tokens: []token{
+				{Type: tokenWord, Value: "name", Pos: 0},
+				{Type: tokenOp, Value: "=", Pos: 4},
+				{Type: tokenWord, Value: "à", Pos: 5},
+				{Type: tokenWord, Value: "value", Pos: 8},
+				{Type: tokenOp, Value: "=", Pos: 15},
+				{Type: tokenWord, Value: "\x85", Pos: 19},
+				{Type: tokenEOF, Pos: 20},
+			},
+		},
+		{src: "name='Part", errPos: 5},
+		{src: "name=<5", errPos: 4},
+		{src: "not !Part", errPos: 4},
+	}
+	for _, test := range tests {
+		tokens, err := lex(test.src)
+		if test.tokens == nil {
+			var serr *SyntaxError
+			if !errors.As(err, &serr) {
+				t.Errorf("lex(%q): expected syntax error, got %v", test.src, err)
+			} else if serr.Pos != test.errPos {
+				t.Errorf("lex(%q): expected error at %d, got %d", test.src, test.errPos, serr.Pos)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("lex(%q): unexpected error: %v", test.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(tokens, test.tokens) {
+			t.Errorf("lex(%q):\n\tgot  %+v\n\twant %+v", test.src, tokens, test.tokens)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src  string
+		kind Kind
+		// where is the String of the parsed condition, or empty if there is
+		// no condition.
+		where  string
+		errPos int
+	}{
+		{src: "class", kind: KindClass},
+		{src: "Classes", kind: KindClass},
+		{src: "items", kind: KindEnumItem},
+		{src: "property where removed", kind: KindProperty, where: "removed"},
+		{src: "class name=Part", kind: KindClass, where: `name="Part"`},
+		{src: "enumitem where value < -1", kind: KindEnumItem, where: `value<"-1"`},
+		{src: "member where Security=PluginSecurity", kind: KindMember, where: `security="PluginSecurity"`},
+		{src: "function where threadsafety=Safe", kind: KindFunction, where: `ThreadSafety="Safe"`},
+		{src: "class where tags.PreferredParent", kind: KindClass, where: "Tags.PreferredParent"},
+		{
+			src:   "member where security=PluginSecurity and tag=Deprecated and class inherits BasePart",
+			kind:  KindMember,
+			where: `((security="PluginSecurity" and tag="Deprecated") and class inherits BasePart)`,
+		},
+		{
+			src:   "class where not removed and (tag=Service or name=*Service)",
+			kind:  KindClass,
+			where: `(not removed and (tag="Service" or name="*Service"))`,
+		},
+		{
+			src:   "class where name=A or name=B and removed",
+			kind:  KindClass,
+			where: `(name="A" or (name="B" and removed))`,
+		},
+		{src: "enum referenced-by class:Humanoid", kind: KindEnum, where: "referenced-by class:Humanoid"},
+		{src: "item where enum references 'type:Vector3'", kind: KindEnumItem, where: "enum references type:Vector3"},
+		{src: "class where name=à", kind: KindClass, where: `name="à"`},
+		{src: "class\vwhere\u00a0name=Part", kind: KindClass, where: `name="Part"`},
+		{src: "", errPos: 0},
+		{src: "(class)", errPos: 0},
+		{src: "widget", errPos: 0},
+		{src: "class where", errPos: 11},
+		{src: "class where superclass=", errPos: 23},
+		{src: "class where value=1", errPos: 12},
+		{src: "class where members>many", errPos: 20},
+		{src: "class where (removed", errPos: 20},
+		{src: "class where removed)", errPos: 19},
+		{src: "class where removed documented", errPos: 20},
+		{src: "member where widget inherits Part", errPos: 13},
+		{src: "class where inherits", errPos: 20},
+		{src: "class where not", errPos: 15},
+	}
+	for _, test := range tests {
+		q, err := Parse(test.src)
+		if test.kind == "" {
+			var serr *SyntaxError
+			if !errors.As(err, &serr) {
+				t.Errorf("Parse(%q): expected syntax error, got %v", test.src, err)
+			} else if serr.Pos != test.errPos {
+				t.Errorf("Parse(%q): expected error at %d, got %d: %v", test.src, test.errPos, serr.Pos, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error: %v", test.src, err)
+			continue
+		}
+		if q.Kind != test.kind {
+			t.Errorf("Parse(%q): expected kind %s, got %s", test.src, test.kind, q.Kind)
+		}
+		var where string
+		if q.Where != nil {
+			where = q.Where.String()
+		}
+		if where != test.where {
+			t.Errorf("Parse(%q): expected condition %s, got %s", test.src, test.where, where)
+		}
+	}
+}
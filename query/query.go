@@ -0,0 +1,297 @@
+// The query package selects entities with a small expression language. See
+// Parse for the syntax of a query.
+package query
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/robloxapi/rbxapiref/builds"
+	"github.com/robloxapi/rbxapiref/entities"
+)
+
+// Kind is a kind of entity selected by a query.
+type Kind string
+
+const (
+	KindClass    Kind = "class"
+	KindMember   Kind = "member"
+	KindProperty Kind = "property"
+	KindFunction Kind = "function"
+	KindEvent    Kind = "event"
+	KindCallback Kind = "callback"
+	KindEnum     Kind = "enum"
+	KindEnumItem Kind = "enumitem"
+	KindType     Kind = "type"
+)
+
+// kindNames maps each accepted name of a kind to the kind.
+var kindNames = map[string]Kind{
+	"class":      KindClass,
+	"classes":    KindClass,
+	"member":     KindMember,
+	"members":    KindMember,
+	"property":   KindProperty,
+	"properties": KindProperty,
+	"function":   KindFunction,
+	"functions":  KindFunction,
+	"event":      KindEvent,
+	"events":     KindEvent,
+	"callback":   KindCallback,
+	"callbacks":  KindCallback,
+	"enum":       KindEnum,
+	"enums":      KindEnum,
+	"enumitem":   KindEnumItem,
+	"enumitems":  KindEnumItem,
+	"item":       KindEnumItem,
+	"items":      KindEnumItem,
+	"type":       KindType,
+	"types":      KindType,
+}
+
+func parseKind(s string) (Kind, bool) {
+	kind, ok := kindNames[strings.ToLower(s)]
+	return kind, ok
+}
+
+// isMember returns whether the kind selects members.
+func (k Kind) isMember() bool {
+	switch k {
+	case KindMember, KindProperty, KindFunction, KindEvent, KindCallback:
+		return true
+	}
+	return false
+}
+
+// Query is a parsed query.
+type Query struct {
+	// Kind is the kind of entity selected by the query.
+	Kind Kind
+	// Where is the condition that each selected entity must satisfy. Nil if
+	// every entity of the kind is selected.
+	Where Expr
+
+	src string
+}
+
+// String returns the source of the query.
+func (q *Query) String() string {
+	return q.src
+}
+
+// Match returns whether an entity is selected by the query.
+func (q *Query) Match(entity interface{}) bool {
+	if !matchKind(q.Kind, entity) {
+		return false
+	}
+	return q.Where == nil || q.Where.Match(entity)
+}
+
+func matchKind(kind Kind, entity interface{}) bool {
+	switch e := entity.(type) {
+	case *entities.Class:
+		return kind == KindClass
+	case *entities.Member:
+		if kind == KindMember {
+			return true
+		}
+		return kind.isMember() && strings.EqualFold(e.Element.GetMemberType(), string(kind))
+	case *entities.Enum:
+		return kind == KindEnum
+	case *entities.EnumItem:
+		return kind == KindEnumItem
+	case *entities.Type:
+		return kind == KindType
+	}
+	return false
+}
+
+// Run returns each entity of ents that is selected by the query. Classes,
+// enums, and types are ordered by name, and members and items are ordered
+// within their parent as they are in its list. Each entity is a *Class,
+// *Member, *Enum, *EnumItem, or *Type from the entities package. Removed
+// entities are included unless the query excludes them.
+func (q *Query) Run(ents *entities.Entities) []interface{} {
+	var results []interface{}
+	add := func(entity interface{}) {
+		if q.Match(entity) {
+			results = append(results, entity)
+		}
+	}
+	switch {
+	case q.Kind == KindClass:
+		for _, class := range ents.ClassList {
+			add(class)
+		}
+	case q.Kind.isMember():
+		for _, class := range ents.ClassList {
+			for _, member := range class.MemberList {
+				add(member)
+			}
+		}
+	case q.Kind == KindEnum:
+		for _, enum := range ents.EnumList {
+			add(enum)
+		}
+	case q.Kind == KindEnumItem:
+		for _, enum := range ents.EnumList {
+			for _, item := range enum.ItemList {
+				add(item)
+			}
+		}
+	case q.Kind == KindType:
+		for _, typ := range ents.TypeList {
+			add(typ)
+		}
+	}
+	return results
+}
+
+// Run parses and runs a query.
+func Run(ents *entities.Entities, s string) ([]interface{}, error) {
+	q, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return q.Run(ents), nil
+}
+
+// Describe returns the type and full name of an entity returned by a query.
+// The name of a member or enum item is qualified by the name of its parent.
+func Describe(entity interface{}) (typ, name string) {
+	switch e := entity.(type) {
+	case *entities.Class:
+		return "Class", e.ID
+	case *entities.Member:
+		return e.Element.GetMemberType(), e.ID[0] + "." + e.ID[1]
+	case *entities.Enum:
+		return "Enum", e.ID
+	case *entities.EnumItem:
+		return "EnumItem", e.ID[0] + "." + e.ID[1]
+	case *entities.Type:
+		return "Type", e.Element.Category + ":" + e.ID
+	}
+	return "", ""
+}
+
+// Expr is a condition of a query.
+type Expr interface {
+	// Match returns whether an entity satisfies the condition.
+	Match(entity interface{}) bool
+	// String returns the condition in the syntax of a query.
+	String() string
+}
+
+// And matches when both conditions match.
+type And [2]Expr
+
+func (x And) Match(e interface{}) bool { return x[0].Match(e) && x[1].Match(e) }
+func (x And) String() string           { return "(" + x[0].String() + " and " + x[1].String() + ")" }
+
+// Or matches when either condition matches.
+type Or [2]Expr
+
+func (x Or) Match(e interface{}) bool { return x[0].Match(e) || x[1].Match(e) }
+func (x Or) String() string           { return "(" + x[0].String() + " or " + x[1].String() + ")" }
+
+// Not matches when the condition does not match.
+type Not [1]Expr
+
+func (x Not) Match(e interface{}) bool { return !x[0].Match(e) }
+func (x Not) String() string           { return "not " + x[0].String() }
+
+// operators is the set of comparison operators.
+var operators = map[string]bool{
+	"=": true, "!=": true, "~": true,
+	"<": true, "<=": true, ">": true, ">=": true,
+}
+
+// numericOps is the set of operators that compare numbers.
+var numericOps = map[string]bool{
+	"<": true, "<=": true, ">": true, ">=": true,
+}
+
+// Compare matches when a value of a field compares with Value.
+type Compare struct {
+	Field string
+	Op    string
+	Value string
+}
+
+func (x Compare) Match(e interface{}) bool {
+	values := fieldValues(e, x.Field)
+	if x.Op == "!=" {
+		return !(Compare{Field: x.Field, Op: "=", Value: x.Value}).Match(e)
+	}
+	want := strings.ToLower(x.Value)
+	for _, v := range values {
+		v = strings.ToLower(v)
+		switch x.Op {
+		case "=":
+			if ok, _ := path.Match(want, v); ok || v == want {
+				return true
+			}
+		case "~":
+			if strings.Contains(v, want) {
+				return true
+			}
+		default:
+			a, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			b, _ := strconv.ParseFloat(want, 64)
+			switch {
+			case x.Op == "<" && a < b,
+				x.Op == "<=" && a <= b,
+				x.Op == ">" && a > b,
+				x.Op == ">=" && a >= b:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (x Compare) String() string {
+	return x.Field + x.Op + strconv.Quote(x.Value)
+}
+
+// Flag matches when a field has a value that is not empty, "false", or "0".
+type Flag struct {
+	Field string
+}
+
+func (x Flag) Match(e interface{}) bool {
+	for _, v := range fieldValues(e, x.Field) {
+		if v != "" && v != "false" && v != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+func (x Flag) String() string { return x.Field }
+
+// Relation matches when the subject of an entity is related to Target.
+type Relation struct {
+	// Subject is "class", "enum", or "parent". Empty for the entity itself.
+	Subject string
+	// Rel is the relation: "inherits", "inherited-by", "references", or
+	// "referenced-by".
+	Rel    string
+	Target builds.Ref
+}
+
+func (x Relation) Match(e interface{}) bool {
+	subject := subjectOf(e, x.Subject)
+	return subject != nil && matchRelation(subject, x.Rel, x.Target)
+}
+
+func (x Relation) String() string {
+	if x.Subject == "" {
+		return x.Rel + " " + x.Target.String()
+	}
+	return x.Subject + " " + x.Rel + " " + x.Target.String()
+}
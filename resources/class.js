@@ -1,115 +1,38 @@
 "use strict";
 {
-function expandMemberList(element, force) {
+// Returns the list of inherited members controlled by the given element, and
+// the header containing the element.
+function inheritedMemberList(element) {
 	let placehold = element.closest(".inherited-members");
 	if (placehold === null) {
-		return;
+		return [null, null];
 	};
 	let head = placehold.closest("thead");
 	if (head === null || !head.parentElement.classList.contains("index-card")) {
-		return;
+		return [null, null];
 	};
-
-	// Get the a subsequent sibling that matches query. Stop when a sibling
-	// matching boundary is encountered.
-	function nextMatching(element, query, boundary) {
-		do {
-			element = element.nextElementSibling;
-			if (element === null) {
-				break;
-			};
-			if (element.matches(query)) {
-				return element;
-			};
-		} while (boundary && !element.matches(boundary));
-		return null;
-	};
-
-	// Attempt to toggle a list that was loaded previously.
-	let body = nextMatching(head, "tbody.inherited-members-list", "thead");
-	if (body !== null) {
-		if (typeof(force) === "boolean") {
-			if (force) {
-				body.style.display = "";
-			} else {
-				body.style.display = "none";
-			};
-			rbxapiActions.Update(head, element);
-			return;
-		};
-		if (body.style.display === "none") {
-			body.style.display = "";
-		} else {
-			body.style.display = "none";
-		};
-		rbxapiActions.Update(head, element);
-		return;
-	}
-
-	if (force === false) {
-		return;
+	let body = head.nextElementSibling;
+	if (body === null || !body.matches("tbody.inherited-members-list")) {
+		return [null, null];
 	};
+	return [head, body];
+};
 
-	let link = placehold.querySelector("a.element-link");
-	if (link === null || link.href.length === 0) {
+function expandMemberList(element, force) {
+	let [head, body] = inheritedMemberList(element);
+	if (body === null) {
 		return;
 	};
-	let url = link.href
-
-	{
-		// Create a message indicating that data is being loaded. Also use
-		// this message as a "lock", which will usually prevent multiple
-		// requests at once by this element.
-		let loader = nextMatching(head, "tbody.loading-message", "thead")
-		if (loader !== null) {
-			return;
-		};
-		head.insertAdjacentHTML("afterend", '<tbody class="loading-message"><tr><td colspan=3><div class="loading-spinner"></div>Loading...</td></tr></tbody>');
-	};
-
-	function clearLoader(event) {
-		let loader = nextMatching(head, "tbody.loading-message", "thead");
-		if (loader === null) {
-			return;
-		};
-		loader.parentElement.removeChild(loader);
-	};
-
-	function formatSingular(c) {
-		return c + " member";
-	};
-	function formatPlural(c) {
-		return c + " members";
+	let show = body.style.display === "none";
+	if (typeof(force) === "boolean") {
+		show = force;
 	};
-	function onLoaded(event) {
-		if (event.target.response === null) {
-			return;
-		};
-		let body = nextMatching(head, "tbody.inherited-members-list", "thead");
-		if (body !== null) {
-			return;
-		};
-		body = event.target.response.querySelector("#members-index .index-card tbody");
-		if (body === null) {
-			return;
-		};
-		body.classList.add("inherited-members-list");
-		head.insertAdjacentElement("afterend", body);
-		window.rbxapiActions.Link(head, false, ["HideIfZero", body, ">*"])
-		window.rbxapiActions.Link(element, false, ["Count", body, ">*", formatSingular, formatPlural]);
-		rbxapiActions.Update(head, element);
+	if (show) {
+		body.style.display = "";
+	} else {
+		body.style.display = "none";
 	};
-
-	let req = new XMLHttpRequest();
-	req.addEventListener("load", function(event) {
-		onLoaded(event);
-		clearLoader(event);
-	});
-	req.addEventListener("error", clearLoader);
-	req.addEventListener("abort", clearLoader);
-	req.open("GET", url);
-	req.responseType = "document";
-	req.send();
+	rbxapiActions.Update(head, element);
 };
 
 function settingsLoaded() {
@@ -130,11 +53,23 @@ function settingsLoaded() {
 };
 
 function domLoaded() {
+	function formatSingular(c) {
+		return c + " member";
+	};
+	function formatPlural(c) {
+		return c + " members";
+	};
 	for (let parent of document.getElementsByClassName("inherited-members")) {
 		let count = parent.querySelector("a.member-count");
 		if (count === null) {
 			continue;
 		};
+		let [head, body] = inheritedMemberList(count);
+		if (body === null) {
+			continue;
+		};
+		rbxapiActions.Link(head, false, ["HideIfZero", body, ">*"]);
+		rbxapiActions.Link(count, false, ["Count", body, ">*", formatSingular, formatPlural]);
 		count.href = "#";
 		count.title = "Click to toggle visibility of members.";
 		count.addEventListener("click", function(event) {
@@ -123,6 +123,21 @@ function domLoaded() {
 		"#removed-type-list",
 		["HideIfZero", ">*"]
 	);
+	rbxapiActions.QuickLink(
+		"#libraries > header .element-count",
+		"#library-list",
+		["Count", ">*", formatCount]
+	);
+	rbxapiActions.QuickLink(
+		"#removed-libraries > header .element-count",
+		"#removed-library-list",
+		["Count", ">*", formatCount]
+	);
+	rbxapiActions.QuickLink(
+		"#removed-libraries",
+		"#removed-library-list",
+		["HideIfZero", ">*"]
+	);
 
 	initSortClasses();
 };
@@ -252,6 +252,14 @@ function fts_fuzzy_match_async(pattern, database, onComplete) {
 		pattern = pattern.slice(queryTypeSplit+1);
 	};
 	var queryPrimarySplit = pattern.indexOf(".");
+	// Members of superclasses are matched against the member part of a
+	// qualified query that names a class.
+	var inherited = new Set();
+	var memberPattern = "";
+	if (queryPrimarySplit > 0) {
+		inherited = database.superclassNames(pattern.slice(0, queryPrimarySplit));
+		memberPattern = pattern.slice(queryPrimarySplit+1);
+	};
 
 	var ITEMS_PER_CHECK = 1000;         // performance.now can be very slow depending on platform
 
@@ -280,14 +288,15 @@ function fts_fuzzy_match_async(pattern, database, onComplete) {
 			let item = database.item(itemIndex);
 			itemIndex++;
 
-			let len = database.data.getUint8(itemOffset);
-			itemOffset++;
-			item.name = decoder.decode(database.data.buffer.slice(itemOffset, itemOffset + len))
-			itemOffset += len;
+			[item.name, itemOffset] = database.readString(itemOffset, decoder);
 
 			if (queryType) {
 				let type = item.dbType;
-				if (queryType === "member") {
+				if (queryType === "library") {
+					if (!item.library) {
+						continue;
+					};
+				} else if (queryType === "member") {
 					switch (type) {
 					case "property":
 					case "function":
@@ -306,6 +315,18 @@ function fts_fuzzy_match_async(pattern, database, onComplete) {
 				continue;
 			};
 
+			if (inherited.size > 0 && memberPattern.length > 0) {
+				let split = item.name.indexOf(".");
+				if (split >= 0 && inherited.has(item.name.slice(0,split).toLowerCase())) {
+					let result = fuzzy_match(memberPattern, item.name.slice(split+1));
+					if (result[0] === true) {
+						result[2] = item.name.slice(0,split+1) + result[2];
+						results.push([result, item]);
+						continue;
+					};
+				};
+			};
+
 			let prefix = "";
 			let suffix = item.name;
 			if (queryPrimarySplit < 0) {
@@ -374,6 +395,7 @@ let validEntityTypes = new Set([
 	"function",
 	"event",
 	"callback",
+	"library",
 ])
 
 class DatabaseItem {
@@ -381,6 +403,7 @@ class DatabaseItem {
 		this.data = data;
 		this.name = string;
 		this.iconIndex = 0;
+		this.channels = 0;
 	};
 	get removed() {
 		return !!getbit(this.data, 3);
@@ -397,6 +420,9 @@ class DatabaseItem {
 		};
 		return null;
 	};
+	get library() {
+		return !!getbit(this.data, 7);
+	};
 	get hidden() {
 		if (getbits(this.data, 0, 3) == 4) {
 			return !!getbit(this.data, 6);
@@ -429,6 +455,9 @@ class DatabaseItem {
 	get dbType() {
 		switch (getbits(this.data, 0, 3)) {
 		case 0:
+			if (this.library) {
+				return "library";
+			};
 			return "class";
 		case 1:
 			return "enum";
@@ -451,6 +480,10 @@ class DatabaseItem {
 		let icon = null;
 		switch (getbits(this.data, 0, 3)) {
 		case 0:
+			if (this.library) {
+				icon = {class: "member-icon", index: 0};
+				break;
+			};
 			icon = {class: "class-icon", index: this.iconIndex || 0};
 			break;
 		case 1:
@@ -488,17 +521,168 @@ class Database {
 		this.ICON_SIZE = 1;
 		this.ITEM_SIZE = 2;
 
-		this.VERSION      = 0;
-		this.ICON_COUNT   = this.VERSION      + 1;
-		this.CLASS_OFFSET = this.ICON_COUNT   + 2;
-		this.ITEM_COUNT   = this.CLASS_OFFSET + 2;
-		this.ICONS        = this.ITEM_COUNT   + 2;
-		this.ITEMS        = this.ICONS        + this.ICON_SIZE*this.iconCount;
-		this.STRINGS      = this.ITEMS        + this.ITEM_SIZE*this.itemCount;
+		this.CHANNEL_SIZE = 1;
+
+		this.VERSION       = 0;
+		this.ICON_COUNT    = this.VERSION      + 1;
+		this.CLASS_OFFSET  = this.ICON_COUNT   + 2;
+		this.ITEM_COUNT    = this.CLASS_OFFSET + 2;
+		if (this.version >= 2) {
+			this.CHANNEL_COUNT = this.ITEM_COUNT + 2;
+			this.ICONS         = this.CHANNEL_COUNT + 1;
+		} else {
+			this.CHANNEL_COUNT = null;
+			this.ICONS         = this.ITEM_COUNT + 2;
+		};
+		this.ITEMS         = this.ICONS        + this.ICON_SIZE*this.iconCount;
+		this.CHANNELS      = this.ITEMS        + this.ITEM_SIZE*this.itemCount;
+		this.STRINGS       = this.CHANNELS;
+		if (this.channelCount > 0) {
+			this.STRINGS += this.CHANNEL_SIZE*this.itemCount;
+		};
+		this._channelNames = null;
+		this._superclasses = null;
+		this._classIndices = null;
 	};
 	get version() {
 		return this.data.getUint8(this.VERSION);
 	};
+	get channelCount() {
+		if (this.CHANNEL_COUNT === null) {
+			return 0;
+		};
+		return this.data.getUint8(this.CHANNEL_COUNT);
+	};
+	// Reads the string at the given offset. Returns the string, and the
+	// offset following the string.
+	readString(off, decoder) {
+		let len = 0;
+		if (this.version >= 3) {
+			let shift = 0;
+			while (off < this.data.byteLength) {
+				let b = this.data.getUint8(off);
+				off++;
+				len += (b & 0x7F) * Math.pow(2, shift);
+				if (b < 0x80) {
+					break;
+				};
+				shift += 7;
+			};
+		} else {
+			len = this.data.getUint8(off);
+			off++;
+		};
+		if (decoder === null) {
+			return [null, off + len];
+		};
+		return [decoder.decode(this.data.buffer.slice(off, off + len)), off + len];
+	};
+	// Reads the sections following the item strings.
+	readTrailer() {
+		let names = [];
+		let count = this.channelCount;
+		this._superclasses = -1;
+		if (count > 0 || this.version >= 4) {
+			let off = this.STRINGS;
+			for (let i = 0; i < this.itemCount && off < this.data.byteLength; i++) {
+				off = this.readString(off, null)[1];
+			};
+			let decoder = new TextDecoder();
+			for (let i = 0; i < count && off < this.data.byteLength; i++) {
+				let name;
+				[name, off] = this.readString(off, decoder);
+				names.push(name);
+			};
+			if (this.version >= 4) {
+				this._superclasses = off;
+			};
+		};
+		this._channelNames = names;
+	};
+	// Returns the list of channel names, which follow the item strings.
+	get channelNames() {
+		if (this._channelNames === null) {
+			this.readTrailer();
+		};
+		return this._channelNames;
+	};
+	// Returns the class index of the superclass of the class at the given
+	// class index, or null if the class has no superclass.
+	superclass(index) {
+		if (this._superclasses === null) {
+			this.readTrailer();
+		};
+		if (this._superclasses < 0) {
+			return null;
+		};
+		let off = this._superclasses + 2*(index % this.iconCount);
+		if (off + 2 > this.data.byteLength) {
+			return null;
+		};
+		let parent = this.data.getUint16(off, true);
+		if (parent === 0xFFFF) {
+			return null;
+		};
+		return parent;
+	};
+	// Returns the class index of the class with the given name, compared
+	// case-insensitively. Returns null if there is no such class.
+	classIndex(name) {
+		if (this._classIndices === null) {
+			this._classIndices = new Map();
+			let indices = [];
+			for (let i = 0; i < this.iconCount; i++) {
+				indices.push(this.classOffset + i);
+			};
+			let names = this.string(indices);
+			for (let i = 0; i < names.length; i++) {
+				if (names[i] !== null) {
+					this._classIndices.set(names[i].toLowerCase(), i);
+				};
+			};
+		};
+		let index = this._classIndices.get(name.toLowerCase());
+		if (index === undefined) {
+			return null;
+		};
+		return index;
+	};
+	// Returns the set of lowercase names of the superclasses of the class
+	// with the given name.
+	superclassNames(name) {
+		let names = new Set();
+		let index = this.classIndex(name);
+		if (index === null) {
+			return names;
+		};
+		let seen = new Set([index]);
+		for (index = this.superclass(index); index !== null && !seen.has(index); index = this.superclass(index)) {
+			seen.add(index);
+			let parent = this.string(this.classOffset + index);
+			if (parent !== null) {
+				names.add(parent.toLowerCase());
+			};
+		};
+		return names;
+	};
+	// Returns the names of the channels indicated by the given bit mask.
+	channels(mask) {
+		let names = [];
+		let list = this.channelNames;
+		for (let i = 0; i < list.length; i++) {
+			if (getbit(mask, i)) {
+				names.push(list[i]);
+			};
+		};
+		return names;
+	};
+	itemChannels(index) {
+		if (this.channelCount === 0) {
+			return 0;
+		};
+		index = index % this.itemCount;
+		return this.data.getUint8(this.CHANNELS + this.CHANNEL_SIZE*index);
+	};
 	get iconCount() {
 		return this.data.getUint16(this.ICON_COUNT, true);
 	};
@@ -528,13 +712,13 @@ class Database {
 		let i = 0;
 		let n = this.itemCount;
 		let off = this.STRINGS;
+		let decoder = new TextDecoder();
 		while (off < this.data.byteLength && i < n) {
-			let len = this.data.getUint8(off);
-			off++;
+			let [str, next] = this.readString(off, decoder);
 			for (let j = 0; j < indices.length; j++) {
 				let index = indices[j] % this.itemCount;
 				if (i === index) {
-					strings[j] = new TextDecoder().decode(this.data.buffer.slice(off, off + len));
+					strings[j] = str;
 					filled++
 					if (filled >= strings.length) {
 						if (single) {
@@ -544,7 +728,7 @@ class Database {
 					};
 				};
 			};
-			off += len;
+			off = next;
 			i++;
 		};
 		if (single) {
@@ -555,6 +739,7 @@ class Database {
 	item(index, useString) {
 		index = index % this.itemCount;
 		let item = new DatabaseItem(this.itemData(index))
+		item.channels = this.itemChannels(index);
 		if (useString) {
 			item.name = this.string(index)
 		};
@@ -625,6 +810,15 @@ function generateLink(item, devhub) {
 		parent = item.name.slice(0,split);
 		member = item.name.slice(split+1);
 	};
+	if (item.library) {
+		if (devhub) {
+			return "";
+		};
+		if (item.dbType === "library") {
+			return pathSub+"/library/"+doubleEncode(member)+".html";
+		};
+		return pathSub+"/library/"+doubleEncode(parent)+".html#member-"+doubleEncode(member);
+	};
 	if (devhub) {
 		switch (item.dbType) {
 		case "class":
@@ -797,6 +991,17 @@ function initSearch() {
 				link.innerHTML += result[0][2];
 				item.appendChild(link);
 			};
+			if (database !== null && result[1].channels !== 0) {
+				// Only show channels for items that do not exist in every
+				// channel.
+				let all = (1 << database.channelCount) - 1;
+				if (result[1].channels !== all) {
+					let channels = document.createElement("span");
+					channels.classList.add("channels");
+					channels.appendChild(document.createTextNode(database.channels(result[1].channels).join(", ")));
+					item.appendChild(channels);
+				};
+			};
 			if (!result[1].removed) {
 				let u = generateLink(result[1], true);
 				if (u !== "") {
@@ -24,6 +24,9 @@ const (
 	ClassPath           = "class"
 	EnumPath            = "enum"
 	TypePath            = "type"
+	LibraryPath         = "library"
+	DiffPath            = "diff"
+	ChannelPath         = "channel"
 	FileExt             = ".html"
 	MemberAnchorPrefix  = "member-"
 	SectionAnchorPrefix = "section-"
@@ -88,6 +91,7 @@ var Default = &Settings{
 			"Archive",
 			"Production",
 		},
+		Channel: "Production",
 	},
 }
 
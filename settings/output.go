@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"unicode"
+
+	"github.com/robloxapi/rbxapiref/manifest"
 )
 
 type Output struct {
@@ -22,8 +24,32 @@ type Output struct {
 	DocResources string
 	// Manifest is the path relative to Sub that points to the manifest file.
 	Manifest string
+	// Compression is the compression applied when writing the manifest file.
+	Compression manifest.Compression
+	// EntityCache is the path to the file caching generated entities between
+	// runs. Unlike other output files, the cache is not part of the site, and
+	// a relative path is relative to the working directory. Caching is
+	// disabled if empty.
+	EntityCache string
 	// Host is the host part of the absolute URL of the site.
 	Host string
+	// SecurityContext is the security context from which the generated API is
+	// viewed, such as "None" for what ordinary scripts can use, or
+	// "PluginSecurity" for what plugins can use. Members that require a more
+	// privileged context are omitted from pages and the search database. The
+	// entire API is generated if empty.
+	SecurityContext string
+	// Diffs is a list of pages that compare the API of two arbitrary builds.
+	Diffs []DiffPage
+}
+
+// DiffPage specifies a page that compares the API of two builds.
+type DiffPage struct {
+	// From refers to the build being compared from. May be the hash, version,
+	// or date of a build, as accepted by builds.FindPatch.
+	From string
+	// To refers to the build being compared to.
+	To string
 }
 
 // Escape once to escape the file name, then again to escape the URL.
@@ -108,10 +134,25 @@ retry:
 			}
 			s = path.Join(TypePath, doubleEscape(args[1])+FileExt)
 		}
+	case "library":
+		if len(args) == 1 {
+			s = path.Join(LibraryPath, doubleEscape(args[0])+FileExt)
+		} else if len(args) == 2 {
+			s = path.Join(LibraryPath, doubleEscape(args[0])+FileExt) +
+				(&url.URL{Fragment: MemberAnchorPrefix + args[1]}).String()
+		}
 	case "about":
 		s = "about" + FileExt
 	case "docmon":
 		s = "docmon" + FileExt
+	case "security":
+		s = "security" + FileExt
+	case "anomalies":
+		s = "anomalies" + FileExt
+	case "diff":
+		s = path.Join(DiffPath, doubleEscape(args[0]+"-"+args[1])+FileExt)
+	case "channel":
+		s = path.Join(ChannelPath, doubleEscape(args[0])+FileExt)
 	case "search":
 		s = "search.db"
 	case "manifest":
@@ -212,6 +253,14 @@ func (o *Output) ParseDocReference(ref string) (scheme, path, link string) {
 	case "type":
 		link = o.FileLink("type", unescapeURLPath(path))
 		return
+	case "library":
+		slash := strings.IndexByte(path, '/')
+		if slash < 0 {
+			link = o.FileLink("library", unescapeURLPath(path))
+			return
+		}
+		link = o.FileLink("library", unescapeURLPath(path[:slash]), unescapeURLPath(path[slash+1:]))
+		return
 	case "member":
 		link = o.FileLink("member", unescapeURLPath(path))
 		return
@@ -10,6 +10,7 @@ import (
 	"github.com/robloxapi/rbxapiref/builds"
 	"github.com/robloxapi/rbxapiref/fetch"
 	"github.com/robloxapi/rbxapiref/internal/binio"
+	"github.com/robloxapi/rbxapiref/manifest"
 )
 
 type Settings struct {
@@ -34,6 +35,13 @@ type Input struct {
 	// committed content will be used. That is, untracked files are ignored, and
 	// only committed modifications to a file are used.
 	UseGit bool
+	// Replacements is the path to a file that maps removed elements to the
+	// elements that replace them. See entities.ReadReplacements for the
+	// format. Optional.
+	Replacements string
+	// Libraries is the path to a JSON file that describes Lua libraries and
+	// globals. See entities.ReadLibraries for the format. Optional.
+	Libraries string
 }
 
 func (settings *Settings) ReadFrom(r io.Reader) (n int64, err error) {
@@ -45,19 +53,29 @@ func (settings *Settings) ReadFrom(r io.Reader) (n int64, err error) {
 			Documents    *string
 			DocResources *string
 			UseGit       *bool
+			Replacements *string
+			Libraries    *string
 		}
 		Output struct {
-			Root         *string
-			Sub          *string
-			Resources    *string
-			DocResources *string
-			Manifest     *string
-			Host         *string
+			Root            *string
+			Sub             *string
+			Resources       *string
+			DocResources    *string
+			Manifest        *string
+			Compression     *manifest.Compression
+			EntityCache     *string
+			Host            *string
+			SecurityContext *string
+			Diffs           []DiffPage
 		}
 		Build struct {
 			Configs       map[string]fetch.Config
 			UseConfigs    []string
+			Channel       *string
+			Channels      map[string][]string
+			Rewind        *builds.RewindMode
 			DisableRewind *bool
+			Metadata      *bool
 		}
 	}
 	err = json.NewDecoder(dw).Decode(&jsettings)
@@ -85,19 +103,47 @@ func (settings *Settings) ReadFrom(r io.Reader) (n int64, err error) {
 	mergeString(&settings.Input.Documents, jsettings.Input.Documents, true)
 	mergeString(&settings.Input.DocResources, jsettings.Input.DocResources, true)
 	mergeBool(&settings.Input.UseGit, jsettings.Input.UseGit)
-	mergeBool(&settings.Build.DisableRewind, jsettings.Build.DisableRewind)
+	mergeString(&settings.Input.Replacements, jsettings.Input.Replacements, false)
+	mergeString(&settings.Input.Libraries, jsettings.Input.Libraries, false)
+	mergeString(&settings.Output.EntityCache, jsettings.Output.EntityCache, false)
+	for _, r := range []*string{&settings.Input.Replacements, &settings.Input.Libraries, &settings.Output.EntityCache} {
+		if *r != "" && !filepath.IsAbs(*r) {
+			*r = filepath.Join(wd, *r)
+		}
+	}
+	if jsettings.Build.Rewind != nil {
+		settings.Build.Rewind = *jsettings.Build.Rewind
+	} else if jsettings.Build.DisableRewind != nil && *jsettings.Build.DisableRewind {
+		// Legacy setting.
+		settings.Build.Rewind = builds.RewindDisable
+	}
 	mergeString(&settings.Output.Root, jsettings.Output.Root, true)
 	mergeString(&settings.Output.Sub, jsettings.Output.Sub, false)
 	mergeString(&settings.Output.Manifest, jsettings.Output.Manifest, false)
+	if jsettings.Output.Compression != nil {
+		settings.Output.Compression = *jsettings.Output.Compression
+	}
 	mergeString(&settings.Output.Resources, jsettings.Output.Resources, false)
 	mergeString(&settings.Output.DocResources, jsettings.Output.DocResources, false)
 	mergeString(&settings.Output.Host, jsettings.Output.Host, false)
+	mergeString(&settings.Output.SecurityContext, jsettings.Output.SecurityContext, false)
+	if len(jsettings.Output.Diffs) > 0 {
+		settings.Output.Diffs = append(settings.Output.Diffs[:0], jsettings.Output.Diffs...)
+	}
 	for k, v := range jsettings.Build.Configs {
 		settings.Build.Configs[k] = v
 	}
 	if len(jsettings.Build.UseConfigs) > 0 {
 		settings.Build.UseConfigs = append(settings.Build.UseConfigs[:0], jsettings.Build.UseConfigs...)
 	}
+	mergeString(&settings.Build.Channel, jsettings.Build.Channel, false)
+	if len(jsettings.Build.Channels) > 0 && settings.Build.Channels == nil {
+		settings.Build.Channels = make(map[string][]string, len(jsettings.Build.Channels))
+	}
+	for k, v := range jsettings.Build.Channels {
+		settings.Build.Channels[k] = v
+	}
+	mergeBool(&settings.Build.Metadata, jsettings.Build.Metadata)
 
 	return dw.End()
 }
@@ -174,5 +220,13 @@ func (settings *Settings) Copy() *Settings {
 	}
 	c.Build.UseConfigs = make([]string, len(settings.Build.UseConfigs))
 	copy(c.Build.UseConfigs, settings.Build.UseConfigs)
+	if settings.Build.Channels != nil {
+		c.Build.Channels = make(map[string][]string, len(settings.Build.Channels))
+		for k, v := range settings.Build.Channels {
+			c.Build.Channels[k] = append([]string(nil), v...)
+		}
+	}
+	c.Output.Diffs = make([]DiffPage, len(settings.Output.Diffs))
+	copy(c.Output.Diffs, settings.Output.Diffs)
 	return &c
 }